@@ -0,0 +1,28 @@
+// Package decimalquery 把查询结果转换为 shopspring/decimal 的精确十进制数
+// 单独成包，核心包不引入decimal依赖
+package decimalquery
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+
+	contracts "github.com/agol586/theattic/multi_token_query"
+)
+
+// DecimalBalance 返回按Decimals换算后的精确余额，如 1500000（6位小数）返回 1.5
+// 余额为nil时返回0
+func DecimalBalance(t contracts.TokenInfo) decimal.Decimal {
+	if t.Balance == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(t.Balance, -int32(t.Decimals))
+}
+
+// DecimalBalances 返回结果中每个token的精确余额，以token地址为键
+func DecimalBalances(r *contracts.QueryResult) map[common.Address]decimal.Decimal {
+	balances := make(map[common.Address]decimal.Decimal, len(r.Tokens))
+	for _, token := range r.Tokens {
+		balances[token.TokenAddress] = DecimalBalance(token)
+	}
+	return balances
+}
@@ -0,0 +1,85 @@
+// Package localequery 按地区习惯格式化余额（千位分隔符、小数点符号）
+// 单独成包，核心包不引入 golang.org/x/text 依赖
+package localequery
+
+import (
+	"math/big"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+
+	contracts "github.com/agol586/theattic/multi_token_query"
+)
+
+// FormattedBalanceLocale 按locale（BCP 47，如 "en-US"、"de-DE"、"fr"）格式化token余额，
+// 如 1234567.5 在 en 下为 "1,234,567.5"，在 de 下为 "1.234.567,5"
+//
+// 数值按decimals基于 big.Rat 精确换算，不会丢失精度。
+// 只支持每3位一组的分组方式，数字始终使用ASCII字符；locale无法解析时按英语格式化
+func FormattedBalanceLocale(t contracts.TokenInfo, locale string) string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.English
+	}
+	group, decimal := separators(tag)
+	return localize(formatBalance(t), group, decimal)
+}
+
+// formatBalance 按decimals精确换算余额，去掉末尾多余的0，如 1500000（6位小数）返回 "1.5"
+func formatBalance(t contracts.TokenInfo) string {
+	if t.Balance == nil {
+		return "0"
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Decimals)), nil)
+	s := new(big.Rat).SetFrac(t.Balance, unit).FloatString(int(t.Decimals))
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// separators 通过格式化一个样例数字得到该地区的千位分隔符和小数点符号，group为空表示不分组
+func separators(tag language.Tag) (group, decimal string) {
+	sample := message.NewPrinter(tag).Sprint(number.Decimal(1234.5, number.MinFractionDigits(1)))
+
+	var symbols []string
+	for _, r := range sample {
+		if !unicode.IsDigit(r) {
+			symbols = append(symbols, string(r))
+		}
+	}
+	switch len(symbols) {
+	case 0:
+		return "", "."
+	case 1:
+		return "", symbols[0]
+	default:
+		return symbols[0], symbols[len(symbols)-1]
+	}
+}
+
+// localize 把"-1234.5"形式的十进制字符串换成指定的分隔符
+func localize(s, group, decimal string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range intPart {
+		if i > 0 && group != "" && (len(intPart)-i)%3 == 0 {
+			b.WriteString(group)
+		}
+		b.WriteRune(r)
+	}
+	if hasFrac {
+		b.WriteString(decimal)
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+// Package otelquery 基于 OpenTelemetry 实现 contracts.Tracer
+// 单独成包，核心包不引入otel依赖
+package otelquery
+
+import (
+	"context"
+	"math/big"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	contracts "github.com/agol586/theattic/multi_token_query"
+)
+
+// instrumentationName otel tracer的名称
+const instrumentationName = "github.com/agol586/theattic/multi_token_query"
+
+// Tracer 把查询span上报到 OpenTelemetry
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer 使用给定的 TracerProvider 创建 Tracer，通常传入 otel.GetTracerProvider()
+//
+//	client, err := contracts.NewMultiTokenQueryClient(rpcURL, addr,
+//		contracts.WithTracer(otelquery.NewTracer(otel.GetTracerProvider())))
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+// StartSpan 实现 contracts.Tracer，新span的父span取自ctx
+func (t *Tracer) StartSpan(ctx context.Context, name string, attrs contracts.SpanAttributes) (context.Context, contracts.SpanEnd) {
+	kv := []attribute.KeyValue{attribute.Int("token.count", attrs.TokenCount)}
+	if attrs.UserLabel != "" {
+		kv = append(kv, attribute.String("user.address", attrs.UserLabel))
+	}
+	if attrs.BlockNumber != nil {
+		kv = append(kv, attribute.String("block.requested", attrs.BlockNumber.String()))
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(kv...))
+
+	return ctx, func(blockNumber *big.Int, err error) {
+		if blockNumber != nil {
+			span.SetAttributes(attribute.String("block.number", blockNumber.String()))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
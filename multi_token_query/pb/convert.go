@@ -0,0 +1,132 @@
+// Package pb 提供 QueryResult 的protobuf表示，供gRPC服务使用
+// 单独成包，避免核心包引入protobuf依赖
+package pb
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	contracts "github.com/agol586/theattic/multi_token_query"
+)
+
+// ToProto 把 QueryResult 转换为protobuf结构，地址序列化为20字节，大整数序列化为十进制字符串
+func ToProto(r *contracts.QueryResult) *QueryResult {
+	if r == nil {
+		return nil
+	}
+
+	out := &QueryResult{
+		QueryAddress:  r.QueryAddress.Bytes(),
+		Tokens:        make([]*TokenInfo, len(r.Tokens)),
+		Timestamp:     bigToString(r.Timestamp),
+		BlockNumber:   bigToString(r.BlockNumber),
+		BaseFee:       bigToString(r.BaseFee),
+		ReorgDetected: r.ReorgDetected,
+	}
+	for i, token := range r.Tokens {
+		out.Tokens[i] = &TokenInfo{
+			TokenAddress: token.TokenAddress.Bytes(),
+			Symbol:       token.Symbol,
+			Decimals:     uint32(token.Decimals),
+			Balance:      bigToString(token.Balance),
+		}
+	}
+	for _, failure := range r.Failures {
+		msg := ""
+		if failure.Err != nil {
+			msg = failure.Err.Error()
+		}
+		out.Failures = append(out.Failures, &TokenFailure{Token: failure.Token.Bytes(), Error: msg})
+	}
+
+	return out
+}
+
+// FromProto 把protobuf结构还原为 QueryResult
+func FromProto(p *QueryResult) (*contracts.QueryResult, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	queryAddress, err := bytesToAddress(p.QueryAddress)
+	if err != nil {
+		return nil, fmt.Errorf("query_address: %v", err)
+	}
+
+	r := &contracts.QueryResult{
+		QueryAddress:  queryAddress,
+		Tokens:        make([]contracts.TokenInfo, len(p.Tokens)),
+		ReorgDetected: p.ReorgDetected,
+	}
+	if r.Timestamp, err = stringToBig(p.Timestamp); err != nil {
+		return nil, fmt.Errorf("timestamp: %v", err)
+	}
+	if r.BlockNumber, err = stringToBig(p.BlockNumber); err != nil {
+		return nil, fmt.Errorf("block_number: %v", err)
+	}
+	if r.BaseFee, err = stringToBig(p.BaseFee); err != nil {
+		return nil, fmt.Errorf("base_fee: %v", err)
+	}
+
+	for i, token := range p.Tokens {
+		address, err := bytesToAddress(token.TokenAddress)
+		if err != nil {
+			return nil, fmt.Errorf("tokens[%d].token_address: %v", i, err)
+		}
+		if token.Decimals > 255 {
+			return nil, fmt.Errorf("tokens[%d].decimals 超出uint8范围: %d", i, token.Decimals)
+		}
+		balance, err := stringToBig(token.Balance)
+		if err != nil {
+			return nil, fmt.Errorf("tokens[%d].balance: %v", i, err)
+		}
+		r.Tokens[i] = contracts.TokenInfo{
+			TokenAddress: address,
+			Symbol:       token.Symbol,
+			Decimals:     uint8(token.Decimals),
+			Balance:      balance,
+		}
+	}
+
+	for i, failure := range p.Failures {
+		address, err := bytesToAddress(failure.Token)
+		if err != nil {
+			return nil, fmt.Errorf("failures[%d].token: %v", i, err)
+		}
+		var failureErr error
+		if failure.Error != "" {
+			failureErr = errors.New(failure.Error)
+		}
+		r.Failures = append(r.Failures, contracts.TokenFailure{Token: address, Err: failureErr})
+	}
+
+	return r, nil
+}
+
+func bigToString(n *big.Int) string {
+	if n == nil {
+		return ""
+	}
+	return n.String()
+}
+
+func stringToBig(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的十进制整数: %q", s)
+	}
+	return n, nil
+}
+
+func bytesToAddress(b []byte) (common.Address, error) {
+	if len(b) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("地址长度应为%d字节，实际为%d", common.AddressLength, len(b))
+	}
+	return common.BytesToAddress(b), nil
+}
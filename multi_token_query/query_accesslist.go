@@ -0,0 +1,55 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// WithAccessList 在每次合约调用的 eth_call 中附带预先计算的访问列表（EIP-2930）
+//
+// 面向高频查询的场景：访问列表让节点预先加载查询会读取的账户和存储槽，可通过 CreateAccessList 生成。
+// 启用后与 WithStateOverride 一样不再通过 bind 发起调用，而是直接发送 eth_call JSON-RPC 请求。
+// 需要节点支持 eth_call 中的 accessList 字段（geth 1.10 及以后、erigon、nethermind 支持），
+// 不支持的节点会忽略或拒绝该字段；列表与实际访问不符不影响结果，只影响节点的执行开销
+func WithAccessList(list types.AccessList) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.accessList = list
+	}
+}
+
+// accessListResult eth_createAccessList 的返回值
+type accessListResult struct {
+	AccessList types.AccessList `json:"accessList"`
+	GasUsed    hexutil.Uint64   `json:"gasUsed"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// CreateAccessList 通过 eth_createAccessList 为查询这一组token生成访问列表，可传给 WithAccessList
+// 需要节点支持 eth_createAccessList（geth 1.10 及以后、erigon、nethermind）
+func (c *MultiTokenQueryClient) CreateAccessList(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) (types.AccessList, error) {
+	input, err := c.abi.Pack("queryMultipleTokens", userAddress, tokenAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := map[string]interface{}{
+		"to":   c.contractAddress,
+		"data": hexutil.Bytes(input),
+	}
+
+	ctx, stop := c.closeAware(ctx)
+	defer stop()
+	var result accessListResult
+	if err := c.conn().client.Client().CallContext(ctx, &result, "eth_createAccessList", msg, "latest"); err != nil {
+		return nil, fmt.Errorf("生成访问列表失败: %w", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("生成访问列表失败: %w", classifyCallError(errors.New(result.Error)))
+	}
+	return result.AccessList, nil
+}
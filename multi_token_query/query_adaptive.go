@@ -0,0 +1,135 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// congestionFactor 单次调用耗时超过观测到的最小耗时的多少倍时视为节点过载
+const congestionFactor = 2
+
+// WithAdaptiveConcurrency 让批量查询的并发数在[min, max]之间按观测到的延迟和错误自动调整（AIMD）：
+// 调用成功且耗时正常时并发数缓慢增加（每轮约加1），遇到错误、HTTP 429或耗时明显变长时减半。
+// 初始并发数为 WithConcurrency 的值（限制在[min, max]内）。
+// 适合同一份代码对接快慢不一的节点服务商，不必为每个节点单独调并发数
+func WithAdaptiveConcurrency(min, max int) Option {
+	return func(c *MultiTokenQueryClient) {
+		if min < 1 {
+			min = 1
+		}
+		if max < min {
+			max = min
+		}
+		c.adaptive = &aimdLimiter{min: float64(min), max: float64(max)}
+	}
+}
+
+// ConcurrencyLimit 返回批量查询当前的并发上限，启用 WithAdaptiveConcurrency 时为当前调整到的值
+func (c *MultiTokenQueryClient) ConcurrencyLimit() int {
+	if c.adaptive == nil {
+		return c.concurrency
+	}
+	return c.adaptive.current()
+}
+
+// aimdLimiter 加性增、乘性减的并发限制器
+type aimdLimiter struct {
+	min, max float64
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    float64
+	inflight int
+	// minLatency 观测到的最小耗时，作为节点未过载时的基准
+	minLatency time.Duration
+	// sinceDecrease 上次减半以来完成的调用数，一轮（limit次）内最多减半一次，避免同一批并发的失败把并发数连续砍到底
+	sinceDecrease int
+	// onChange 并发上限变化时调用，可为nil
+	onChange func(limit int)
+}
+
+// init 设置初始并发数
+func (l *aimdLimiter) init(initial int) {
+	l.cond = sync.NewCond(&l.mu)
+	l.limit = l.clamp(float64(initial))
+	l.sinceDecrease = int(l.limit)
+}
+
+func (l *aimdLimiter) clamp(v float64) float64 {
+	if v < l.min {
+		return l.min
+	}
+	if v > l.max {
+		return l.max
+	}
+	return v
+}
+
+func (l *aimdLimiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// acquire 等待直到进行中的调用数低于当前上限
+func (l *aimdLimiter) acquire() {
+	l.mu.Lock()
+	for l.inflight >= int(l.limit) {
+		l.cond.Wait()
+	}
+	l.inflight++
+	l.mu.Unlock()
+}
+
+// release 释放一个并发名额，并根据本次调用的耗时和错误调整上限
+func (l *aimdLimiter) release(latency time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	l.sinceDecrease++
+
+	// 调用方取消和合约revert与节点负载无关，不参与调整
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrRevert) {
+		l.cond.Broadcast()
+		return
+	}
+
+	if err == nil && (l.minLatency == 0 || latency < l.minLatency) {
+		l.minLatency = latency
+	}
+
+	before := int(l.limit)
+	// 节点错误（包括HTTP 429限流）和明显变慢都视为过载
+	if err != nil || latency > congestionFactor*l.minLatency {
+		if l.sinceDecrease >= before {
+			l.limit = l.clamp(l.limit / 2)
+			l.sinceDecrease = 0
+		}
+	} else {
+		l.limit = l.clamp(l.limit + 1/l.limit)
+	}
+
+	if after := int(l.limit); after != before && l.onChange != nil {
+		l.onChange(after)
+	}
+	l.cond.Broadcast()
+}
+
+// runAdaptive 与 runBounded 相同，但并发数由l动态控制，fn 返回的错误用于调整并发数
+func runAdaptive(l *aimdLimiter, n int, fn func(i int) error) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		l.acquire()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := time.Now()
+			err := fn(i)
+			l.release(time.Since(start), err)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,52 @@
+package contracts
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAdaptiveConcurrencyFollowsLatency(t *testing.T) {
+	client, node := newFakeClient(t, WithConcurrency(4), WithAdaptiveConcurrency(1, 16))
+	tokens := node.addTokens(2)
+	users := make([]common.Address, 24)
+	for i := range users {
+		users[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+	ctx := context.Background()
+
+	// 延迟稳定时逐步增加并发数
+	node.setLatency(20 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if _, err := client.QueryMultipleTokensBatch(ctx, users, tokens); err != nil {
+			t.Fatal(err)
+		}
+	}
+	grown := client.ConcurrencyLimit()
+	if grown <= 4 {
+		t.Fatalf("延迟稳定时并发数 = %d，期望大于初始值4", grown)
+	}
+
+	// 节点明显变慢时减半
+	node.setLatency(100 * time.Millisecond)
+	if _, err := client.QueryMultipleTokensBatch(ctx, users[:12], tokens); err != nil {
+		t.Fatal(err)
+	}
+	slowed := client.ConcurrencyLimit()
+	if slowed >= grown {
+		t.Fatalf("节点变慢后并发数 = %d，期望小于 %d", slowed, grown)
+	}
+
+	// 限流错误同样视为过载
+	node.setLatency(0)
+	node.setHook(func(common.Address, []byte) ([]byte, error) {
+		return nil, &fakeRPCError{code: -32005, msg: "rate limit exceeded"}
+	})
+	client.QueryMultipleTokensBatch(ctx, users, tokens)
+	if limited := client.ConcurrencyLimit(); limited >= slowed && slowed > 1 {
+		t.Fatalf("限流后并发数 = %d，期望小于 %d", limited, slowed)
+	}
+}
@@ -0,0 +1,245 @@
+package contracts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrNotAlchemy 节点不支持Alchemy的扩展方法，通常是因为连接的不是Alchemy节点
+var ErrNotAlchemy = errors.New("节点不支持alchemy_getTokenBalances，请确认连接的是Alchemy节点")
+
+// alchemyMaxTokens alchemy_getTokenBalances 单次请求最多的token地址数
+const alchemyMaxTokens = 100
+
+var _ TokenQuerier = (*AlchemyTokenClient)(nil)
+
+// AlchemyTokenClient 通过Alchemy的 alchemy_getTokenBalances 查询余额，实现 TokenQuerier，不需要部署查询合约
+//
+// 与查询合约不同，Alchemy可以不提供token列表直接返回地址持有的全部ERC-20余额（见 QueryAllTokens）。
+// symbol和decimals通过 alchemy_getTokenMetadata 查询并缓存，查询失败的token记入 Failures。
+// 余额不固定在某个区块上：结果的区块号和时间戳取自查询前的最新区块，余额对应的区块可能稍晚于它
+type AlchemyTokenClient struct {
+	rpc      *rpc.Client
+	client   *ethclient.Client
+	metadata *MetadataRegistry
+}
+
+// NewAlchemyTokenClient 连接Alchemy节点，rpcURL 形如 https://eth-mainnet.g.alchemy.com/v2/<api-key>
+// 创建时不访问节点，节点不是Alchemy时在查询时返回 ErrNotAlchemy
+func NewAlchemyTokenClient(rpcURL string) (*AlchemyTokenClient, error) {
+	rpcClient, err := dialRPC(rpcURL, http.DefaultTransport)
+	if err != nil {
+		return nil, fmt.Errorf("连接Alchemy节点%s失败: %v", RedactRPCURL(rpcURL), err)
+	}
+	return &AlchemyTokenClient{
+		rpc:      rpcClient,
+		client:   ethclient.NewClient(rpcClient),
+		metadata: NewMetadataRegistry(),
+	}, nil
+}
+
+// Close 关闭底层连接
+func (a *AlchemyTokenClient) Close() {
+	a.rpc.Close()
+}
+
+// alchemyTokenBalances alchemy_getTokenBalances 的返回
+type alchemyTokenBalances struct {
+	TokenBalances []struct {
+		ContractAddress common.Address  `json:"contractAddress"`
+		TokenBalance    *string         `json:"tokenBalance"`
+		Error           json.RawMessage `json:"error"`
+	} `json:"tokenBalances"`
+	PageKey string `json:"pageKey"`
+}
+
+// alchemyTokenMetadata alchemy_getTokenMetadata 的返回，未知的字段为null
+type alchemyTokenMetadata struct {
+	Symbol   *string `json:"symbol"`
+	Decimals *int    `json:"decimals"`
+}
+
+// QueryMultipleTokens 查询用户在指定token上的余额，token列表为空时等同于 QueryAllTokens
+// token超过100个时拆成多次请求
+func (a *AlchemyTokenClient) QueryMultipleTokens(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+	if len(tokenAddresses) == 0 {
+		return a.QueryAllTokens(ctx, userAddress)
+	}
+
+	result, err := a.newResult(ctx, userAddress)
+	if err != nil {
+		return nil, err
+	}
+	for _, chunk := range chunkTokens(tokenAddresses, alchemyMaxTokens) {
+		var resp alchemyTokenBalances
+		if err := a.callAlchemy(ctx, &resp, "alchemy_getTokenBalances", userAddress, chunk); err != nil {
+			return nil, err
+		}
+		if err := a.appendBalances(ctx, result, &resp, false); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// QueryAllTokens 不提供token列表，查询用户持有的全部ERC-20余额，按Alchemy的分页逐页读取
+// 只返回余额非零的token
+func (a *AlchemyTokenClient) QueryAllTokens(ctx context.Context, userAddress common.Address) (*QueryResult, error) {
+	result, err := a.newResult(ctx, userAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	pageKey := ""
+	for {
+		params := []interface{}{userAddress, "erc20"}
+		if pageKey != "" {
+			params = append(params, map[string]string{"pageKey": pageKey})
+		}
+		var resp alchemyTokenBalances
+		if err := a.callAlchemy(ctx, &resp, "alchemy_getTokenBalances", params...); err != nil {
+			return nil, err
+		}
+		if err := a.appendBalances(ctx, result, &resp, true); err != nil {
+			return nil, err
+		}
+		if resp.PageKey == "" {
+			return result, nil
+		}
+		pageKey = resp.PageKey
+	}
+}
+
+// QueryBalances 按请求顺序返回余额，查询失败的token余额为nil
+func (a *AlchemyTokenClient) QueryBalances(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) ([]*big.Int, *big.Int, *big.Int, error) {
+	result, err := a.QueryMultipleTokens(ctx, userAddress, tokenAddresses)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	byAddress := make(map[common.Address]*big.Int, len(result.Tokens))
+	for _, token := range result.Tokens {
+		byAddress[token.TokenAddress] = token.Balance
+	}
+	balances := make([]*big.Int, len(tokenAddresses))
+	for i, addr := range tokenAddresses {
+		balances[i] = byAddress[addr]
+	}
+	return balances, result.Timestamp, result.BlockNumber, nil
+}
+
+// newResult 创建空结果，区块号和时间戳取自当前最新区块
+func (a *AlchemyTokenClient) newResult(ctx context.Context, userAddress common.Address) (*QueryResult, error) {
+	head, err := a.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询最新区块头失败: %w", err)
+	}
+	return &QueryResult{
+		QueryAddress: userAddress,
+		Tokens:       []TokenInfo{},
+		Timestamp:    new(big.Int).SetUint64(head.Time),
+		BlockNumber:  head.Number,
+	}, nil
+}
+
+// appendBalances 补全元数据后把resp中的余额加入result，nonZeroOnly 时跳过余额为0的token
+func (a *AlchemyTokenClient) appendBalances(ctx context.Context, result *QueryResult, resp *alchemyTokenBalances, nonZeroOnly bool) error {
+	for _, entry := range resp.TokenBalances {
+		if entry.TokenBalance == nil {
+			result.Failures = append(result.Failures, TokenFailure{Token: entry.ContractAddress, Err: alchemyEntryError(entry.Error)})
+			continue
+		}
+		balance, ok := parseAlchemyBalance(*entry.TokenBalance)
+		if !ok {
+			result.Failures = append(result.Failures, TokenFailure{Token: entry.ContractAddress, Err: fmt.Errorf("无法解析余额%q", *entry.TokenBalance)})
+			continue
+		}
+		if nonZeroOnly && balance.Sign() == 0 {
+			continue
+		}
+
+		metadata, err := a.tokenMetadata(ctx, entry.ContractAddress)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			result.Failures = append(result.Failures, TokenFailure{Token: entry.ContractAddress, Err: err})
+			continue
+		}
+		result.Tokens = append(result.Tokens, TokenInfo{
+			TokenAddress: entry.ContractAddress,
+			Symbol:       metadata.Symbol,
+			Decimals:     metadata.Decimals,
+			Balance:      balance,
+		})
+	}
+	return nil
+}
+
+// tokenMetadata 返回token的元数据，已查询过的直接从缓存返回
+func (a *AlchemyTokenClient) tokenMetadata(ctx context.Context, token common.Address) (TokenMetadata, error) {
+	if metadata, ok := a.metadata.Get(token); ok {
+		return metadata, nil
+	}
+
+	var resp alchemyTokenMetadata
+	if err := a.callAlchemy(ctx, &resp, "alchemy_getTokenMetadata", token); err != nil {
+		return TokenMetadata{}, fmt.Errorf("查询token元数据失败: %w", err)
+	}
+	if resp.Decimals == nil || *resp.Decimals < 0 || *resp.Decimals > 255 {
+		return TokenMetadata{}, errors.New("Alchemy没有该token的decimals")
+	}
+
+	metadata := TokenMetadata{Decimals: uint8(*resp.Decimals)}
+	if resp.Symbol != nil {
+		metadata.Symbol = *resp.Symbol
+	}
+	a.metadata.Set(token, metadata)
+	return metadata, nil
+}
+
+// callAlchemy 调用Alchemy扩展方法，节点不认识该方法时返回 ErrNotAlchemy
+func (a *AlchemyTokenClient) callAlchemy(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	err := a.rpc.CallContext(ctx, result, method, params...)
+	if err == nil {
+		return nil
+	}
+	var rpcErr *RPCError
+	if errors.As(wrapRPCError(err), &rpcErr) && rpcErr.MethodNotFound() {
+		return fmt.Errorf("%w: %v", ErrNotAlchemy, err)
+	}
+	if msg := strings.ToLower(err.Error()); strings.Contains(msg, "method not found") || strings.Contains(msg, "does not exist") {
+		return fmt.Errorf("%w: %v", ErrNotAlchemy, err)
+	}
+	return fmt.Errorf("调用%s失败: %w", method, err)
+}
+
+// parseAlchemyBalance 解析十六进制余额，Alchemy返回的值可能带前导0（32字节补齐）或为"0x"
+func parseAlchemyBalance(s string) (*big.Int, bool) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if digits == "" {
+		return new(big.Int), true
+	}
+	return new(big.Int).SetString(digits, 16)
+}
+
+// alchemyEntryError 把单个token的错误字段转换为error
+func alchemyEntryError(raw json.RawMessage) error {
+	var msg string
+	if err := json.Unmarshal(raw, &msg); err == nil && msg != "" {
+		return errors.New(msg)
+	}
+	if len(raw) > 0 && string(raw) != "null" {
+		return errors.New(string(raw))
+	}
+	return errors.New("Alchemy没有返回余额")
+}
@@ -0,0 +1,154 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SeenTokenStore 保存每个钱包已经出现过的token，供 TokenArrivalPoller 在重启后继续判断哪些token是新出现的
+// 实现需要并发安全，可以基于Redis、数据库等持久化存储
+type SeenTokenStore interface {
+	// Seen 返回钱包已出现过的token；known 为false表示从未记录过该钱包
+	Seen(ctx context.Context, wallet common.Address) (tokens map[common.Address]bool, known bool, err error)
+	// MarkSeen 把tokens加入钱包已出现过的集合，tokens为空时也要把钱包记为已知
+	MarkSeen(ctx context.Context, wallet common.Address, tokens []common.Address) error
+}
+
+// MemorySeenTokenStore 内存中的 SeenTokenStore，进程重启后丢失，适合测试和单机场景
+type MemorySeenTokenStore struct {
+	mu      sync.Mutex
+	wallets map[common.Address]map[common.Address]bool
+}
+
+// NewMemorySeenTokenStore 创建空的内存存储
+func NewMemorySeenTokenStore() *MemorySeenTokenStore {
+	return &MemorySeenTokenStore{wallets: make(map[common.Address]map[common.Address]bool)}
+}
+
+// Seen 实现 SeenTokenStore
+func (s *MemorySeenTokenStore) Seen(ctx context.Context, wallet common.Address) (map[common.Address]bool, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen, ok := s.wallets[wallet]
+	if !ok {
+		return nil, false, nil
+	}
+	tokens := make(map[common.Address]bool, len(seen))
+	for token := range seen {
+		tokens[token] = true
+	}
+	return tokens, true, nil
+}
+
+// MarkSeen 实现 SeenTokenStore
+func (s *MemorySeenTokenStore) MarkSeen(ctx context.Context, wallet common.Address, tokens []common.Address) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen, ok := s.wallets[wallet]
+	if !ok {
+		seen = make(map[common.Address]bool, len(tokens))
+		s.wallets[wallet] = seen
+	}
+	for _, token := range tokens {
+		seen[token] = true
+	}
+	return nil
+}
+
+// NewTokenEvent 钱包中第一次出现某个token
+type NewTokenEvent struct {
+	Wallet      common.Address
+	Token       TokenInfo
+	BlockNumber *big.Int
+}
+
+// TokenArrivalPoller 定期查询钱包余额，在某个token第一次出现非零余额时发出事件，用于"收到新token"通知
+//
+// 已出现过的token保存在 SeenTokenStore 中，重启后不会重复通知；token余额归零后再次收到也不会再通知。
+// 某个钱包第一次被轮询时（存储中没有记录）只记录当前持有的token作为基线，不发出事件，避免首次运行时通知所有持仓
+type TokenArrivalPoller struct {
+	client *MultiTokenQueryClient
+	store  SeenTokenStore
+}
+
+// NewTokenArrivalPoller 创建轮询器，store 为nil时使用 NewMemorySeenTokenStore
+func NewTokenArrivalPoller(client *MultiTokenQueryClient, store SeenTokenStore) *TokenArrivalPoller {
+	if store == nil {
+		store = NewMemorySeenTokenStore()
+	}
+	return &TokenArrivalPoller{client: client, store: store}
+}
+
+// Poll 查询一次钱包在tokenAddresses上的余额，返回新出现的token，按tokenAddresses中的顺序排列
+// tokenAddresses 可以来自固定的token列表或 DiscoverTokens 的结果
+func (p *TokenArrivalPoller) Poll(ctx context.Context, wallet common.Address, tokenAddresses []common.Address) ([]NewTokenEvent, error) {
+	seen, known, err := p.store.Seen(ctx, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("读取已出现的token失败: %w", err)
+	}
+
+	result, err := p.client.QueryMultipleTokens(ctx, wallet, tokenAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []NewTokenEvent
+	var arrived []common.Address
+	for _, token := range result.Tokens {
+		if token.Balance == nil || token.Balance.Sign() <= 0 || seen[token.TokenAddress] {
+			continue
+		}
+		arrived = append(arrived, token.TokenAddress)
+		if known {
+			events = append(events, NewTokenEvent{Wallet: wallet, Token: token, BlockNumber: result.BlockNumber})
+		}
+	}
+
+	if len(arrived) > 0 || !known {
+		if err := p.store.MarkSeen(ctx, wallet, arrived); err != nil {
+			return nil, fmt.Errorf("保存已出现的token失败: %w", err)
+		}
+	}
+	return events, nil
+}
+
+// Run 每隔interval轮询一次所有钱包，把新出现的token发送到ch
+// 单个钱包查询失败只记录警告，下一轮重试；一直运行到ctx取消并返回ctx.Err()，不会关闭ch。interval必须大于0，否则立即返回错误
+func (p *TokenArrivalPoller) Run(ctx context.Context, wallets []common.Address, tokenAddresses []common.Address, interval time.Duration, ch chan<- NewTokenEvent) error {
+	if interval <= 0 {
+		return fmt.Errorf("轮询间隔必须大于0: %s", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, wallet := range wallets {
+			events, err := p.Poll(ctx, wallet, tokenAddresses)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				p.client.logger.Warn("轮询新token失败", "user", p.client.walletLabel(wallet), "err", err)
+				continue
+			}
+			for _, event := range events {
+				select {
+				case ch <- event:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenArrivalPollerRunRejectsNonPositiveInterval(t *testing.T) {
+	client, node := newFakeClient(t)
+	poller := NewTokenArrivalPoller(client, nil)
+	ch := make(chan NewTokenEvent, 1)
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := poller.Run(context.Background(), nil, node.addTokens(1), interval, ch); err == nil {
+			t.Errorf("interval = %s 时应返回错误", interval)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BatchResult 尽力模式下的批量查询结果
+type BatchResult struct {
+	// Results 与传入的users一一对应，未能在截止时间前完成的用户为nil
+	Results []*QueryResult
+	// Truncated 为true表示ctx到期时仍有用户未完成查询，Results不完整
+	Truncated bool
+	// Meta 已完成结果的区块范围
+	Meta BatchMeta
+}
+
+// BatchMeta 一批结果的元数据，用于观察批次内各结果所在区块的差异
+// 未启用 WithConsistentSnapshot 时各用户分别查询最新区块，节点较慢或负载均衡到不同节点时区块会有差异，
+// Spread 明显偏大时通常说明节点同步不一致
+type BatchMeta struct {
+	// MinBlock、MaxBlock 结果中最小和最大的区块号，没有任何带区块号的结果时为nil
+	MinBlock *big.Int
+	MaxBlock *big.Int
+}
+
+// NewBatchMeta 统计results（可以包含nil）的区块范围
+func NewBatchMeta(results []*QueryResult) BatchMeta {
+	var meta BatchMeta
+	for _, result := range results {
+		if result == nil || result.BlockNumber == nil {
+			continue
+		}
+		if meta.MinBlock == nil || result.BlockNumber.Cmp(meta.MinBlock) < 0 {
+			meta.MinBlock = result.BlockNumber
+		}
+		if meta.MaxBlock == nil || result.BlockNumber.Cmp(meta.MaxBlock) > 0 {
+			meta.MaxBlock = result.BlockNumber
+		}
+	}
+	if meta.MinBlock != nil {
+		meta.MinBlock = new(big.Int).Set(meta.MinBlock)
+		meta.MaxBlock = new(big.Int).Set(meta.MaxBlock)
+	}
+	return meta
+}
+
+// Spread 返回最大与最小区块号之差，没有区块号时为0
+func (m BatchMeta) Spread() uint64 {
+	if m.MinBlock == nil || m.MaxBlock == nil {
+		return 0
+	}
+	return new(big.Int).Sub(m.MaxBlock, m.MinBlock).Uint64()
+}
+
+// QueryMultipleTokensBatch 并发查询多个用户的多个token信息，返回结果与users顺序一致
+// 并发数由 WithConcurrency 控制。单个用户失败不影响其他用户：失败用户对应的结果为nil，
+// 所有失败通过 errors.Join 合并为一个错误返回，每个错误都带有对应的用户；全部成功时错误为nil。
+// 因此返回错误时结果仍然有效，需要逐个判断是否为nil
+func (c *MultiTokenQueryClient) QueryMultipleTokensBatch(ctx context.Context, users []common.Address, tokenAddresses []common.Address) (_ []*QueryResult, err error) {
+	ctx, end := c.startSpan(ctx, "QueryMultipleTokensBatch", common.Address{}, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(nil, err) }()
+
+	results, errs := c.queryBatch(ctx, users, tokenAddresses)
+	return results, errors.Join(errs...)
+}
+
+// QueryMultipleTokensBatchWithMeta 与 QueryMultipleTokensBatch 相同，同时返回结果的区块范围
+func (c *MultiTokenQueryClient) QueryMultipleTokensBatchWithMeta(ctx context.Context, users []common.Address, tokenAddresses []common.Address) (_ []*QueryResult, _ BatchMeta, err error) {
+	ctx, end := c.startSpan(ctx, "QueryMultipleTokensBatchWithMeta", common.Address{}, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(nil, err) }()
+
+	results, errs := c.queryBatch(ctx, users, tokenAddresses)
+	return results, NewBatchMeta(results), errors.Join(errs...)
+}
+
+// QueryMultipleTokensBatchBestEffort 尽力模式的批量查询
+//
+// ctx到期时不会丢弃已经完成的结果，而是返回截止前拿到的部分结果并设置 Truncated，
+// 适合"2秒内能拿到多少就显示多少"的看板类场景。代价是调用方必须处理结果不完整的情况：
+// 未完成用户对应的位置为nil，不能把缺失当作余额为零。
+// 非超时类的失败仍会通过error返回，此时已完成的结果同样保留在 BatchResult 中。
+func (c *MultiTokenQueryClient) QueryMultipleTokensBatchBestEffort(ctx context.Context, users []common.Address, tokenAddresses []common.Address) (_ *BatchResult, err error) {
+	ctx, end := c.startSpan(ctx, "QueryMultipleTokensBatchBestEffort", common.Address{}, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(nil, err) }()
+
+	results, errs := c.queryBatch(ctx, users, tokenAddresses)
+
+	batch := &BatchResult{Results: results, Meta: NewBatchMeta(results)}
+	var failures []error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil && (errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)) {
+			batch.Truncated = true
+			continue
+		}
+		failures = append(failures, err)
+	}
+
+	return batch, errors.Join(failures...)
+}
+
+// StreamMultipleTokens 并发查询多个用户，每完成一个用户就把结果发送到ch，结果按完成顺序到达，可用 QueryAddress 区分用户
+//
+// ch 的容量即缓冲区大小，由调用方在创建时决定：缓冲区满时查询goroutine阻塞在发送上，直到消费者取走结果，
+// 因此消费者的速度决定查询的节奏，内存中最多积压 缓冲区大小+WithConcurrency 个结果；无缓冲的ch表示完全同步交接。
+// ctx取消时阻塞的发送立即放弃，未开始的用户不再查询。
+// 失败的用户不发送结果，所有失败通过 errors.Join 合并返回，与 QueryMultipleTokensBatch 相同。
+// 所有用户处理完后返回，不会关闭ch，通常由调用方在返回后关闭
+func (c *MultiTokenQueryClient) StreamMultipleTokens(ctx context.Context, users []common.Address, tokenAddresses []common.Address, ch chan<- *QueryResult) (err error) {
+	ctx, end := c.startSpan(ctx, "StreamMultipleTokens", common.Address{}, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(nil, err) }()
+
+	if c.pinSnapshot && len(users) > 0 {
+		snapshotCtx, _, err := c.Snapshot(ctx)
+		if err != nil {
+			return err
+		}
+		ctx = snapshotCtx
+	}
+
+	errs := make([]error, len(users))
+	runBounded(c.concurrency, len(users), func(i int) {
+		if err := ctx.Err(); err != nil {
+			errs[i] = fmt.Errorf("查询用户%s失败: %w", c.walletLabel(users[i]), err)
+			return
+		}
+
+		result, err := c.QueryMultipleTokens(ctx, users[i], tokenAddresses)
+		if err != nil {
+			errs[i] = fmt.Errorf("查询用户%s失败: %w", c.walletLabel(users[i]), err)
+			c.logger.Warn("流式查询中单个用户失败", "user", c.walletLabel(users[i]), "err", err)
+			return
+		}
+
+		select {
+		case ch <- result:
+		case <-ctx.Done():
+			errs[i] = fmt.Errorf("发送用户%s的结果失败: %w", c.walletLabel(users[i]), ctx.Err())
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// queryBatch 并发查询每个用户，返回与users一一对应的结果和错误
+func (c *MultiTokenQueryClient) queryBatch(ctx context.Context, users []common.Address, tokenAddresses []common.Address) ([]*QueryResult, []error) {
+	results := make([]*QueryResult, len(users))
+	errs := make([]error, len(users))
+
+	if c.pinSnapshot && len(users) > 0 {
+		snapshotCtx, _, err := c.Snapshot(ctx)
+		if err != nil {
+			for i := range errs {
+				errs[i] = fmt.Errorf("查询用户%s失败: %w", c.walletLabel(users[i]), err)
+			}
+			return results, errs
+		}
+		ctx = snapshotCtx
+	}
+
+	query := func(i int) error {
+		if err := ctx.Err(); err != nil {
+			errs[i] = fmt.Errorf("查询用户%s失败: %w", c.walletLabel(users[i]), err)
+			return err
+		}
+
+		result, err := c.QueryMultipleTokens(ctx, users[i], tokenAddresses)
+		if err != nil {
+			errs[i] = fmt.Errorf("查询用户%s失败: %w", c.walletLabel(users[i]), err)
+			c.logger.Warn("批量查询中单个用户失败", "user", c.walletLabel(users[i]), "err", err)
+			return err
+		}
+		results[i] = result
+		return nil
+	}
+
+	if c.adaptive != nil {
+		runAdaptive(c.adaptive, len(users), query)
+	} else {
+		runBounded(c.concurrency, len(users), func(i int) { query(i) })
+	}
+
+	return results, errs
+}
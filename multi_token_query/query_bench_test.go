@@ -0,0 +1,47 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func BenchmarkQueryMultipleTokens(b *testing.B) {
+	for _, n := range []int{1, 10, 100, 1000} {
+		b.Run(fmt.Sprintf("tokens=%d", n), func(b *testing.B) {
+			client, node := newFakeClient(b)
+			tokens := node.addTokens(n)
+			user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.QueryMultipleTokens(ctx, user, tokens); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkQueryMultipleTokensBatch(b *testing.B) {
+	client, node := newFakeClient(b)
+	tokens := node.addTokens(100)
+	users := make([]common.Address, 20)
+	for i := range users {
+		users[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.QueryMultipleTokensBatch(ctx, users, tokens); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// binaryFormatVersion MarshalBinary 输出格式的版本，写在第一个字节，格式变化时加1
+// 版本2在合约地址之后增加了ENS名称，UnmarshalBinary 仍可读取版本1的数据
+const binaryFormatVersion = 2
+
+// token标志位
+const (
+	binaryDecimalsClamped = 1 << iota
+	binarySuspiciousDecimals
+	binaryTransferBlocked
+	binaryDecimalsFromChain
+	binaryDecimalsFromRegistry
+	binaryDecimalsUnknown
+	binaryEmptyBalanceData
+)
+
+// binaryDecimalsSources DecimalsSource 与标志位的对应关系
+var binaryDecimalsSources = map[DecimalsSource]byte{
+	DecimalsSourceChain:    binaryDecimalsFromChain,
+	DecimalsSourceRegistry: binaryDecimalsFromRegistry,
+	DecimalsSourceUnknown:  binaryDecimalsUnknown,
+}
+
+// 结果标志位
+const binaryReorgDetected = 1
+
+// errBinaryTruncated 二进制数据在读完所有字段前结束
+var errBinaryTruncated = errors.New("二进制数据不完整")
+
+// MarshalBinary 实现 encoding.BinaryMarshaler，使用紧凑的自定义格式，便于在Redis或内存中缓存大量快照
+//
+// 地址按20字节原样写入，大整数写为 长度和符号 的uvarint加上大端字节，字符串和列表以uvarint长度开头。
+// 对常见的结果，体积约为 json.Marshal 的三分之一到四分之一（主要省去了字段名和十六进制地址）。
+// 格式带版本号，UnmarshalBinary 可以无损还原，TokenFailure 的错误与JSON一样还原为只包含原始信息的error
+func (r *QueryResult) MarshalBinary() ([]byte, error) {
+	buf := []byte{binaryFormatVersion}
+	buf = binary.AppendUvarint(buf, uint64(r.SchemaVersion))
+	buf = append(buf, r.QueryAddress[:]...)
+	buf = append(buf, r.ContractAddress[:]...)
+	buf = appendBinaryString(buf, r.ENSName)
+	var flags byte
+	if r.ReorgDetected {
+		flags |= binaryReorgDetected
+	}
+	buf = append(buf, flags)
+	buf = appendBinaryBig(buf, r.Timestamp)
+	buf = appendBinaryBig(buf, r.BlockNumber)
+	buf = appendBinaryBig(buf, r.BaseFee)
+
+	buf = binary.AppendUvarint(buf, uint64(len(r.Tokens)))
+	for _, token := range r.Tokens {
+		buf = append(buf, token.TokenAddress[:]...)
+		buf = appendBinaryString(buf, token.Symbol)
+		var flags byte
+		if token.DecimalsClamped {
+			flags |= binaryDecimalsClamped
+		}
+		if token.SuspiciousDecimals {
+			flags |= binarySuspiciousDecimals
+		}
+		if token.TransferBlocked {
+			flags |= binaryTransferBlocked
+		}
+		if token.EmptyBalanceData {
+			flags |= binaryEmptyBalanceData
+		}
+		flags |= binaryDecimalsSources[token.DecimalsSource]
+		buf = append(buf, token.Decimals, flags)
+		buf = appendBinaryBig(buf, token.Balance)
+		buf = appendBinaryBig(buf, token.Shares)
+		buf = appendBinaryBig(buf, token.TotalSupply)
+		buf = appendBinaryBig(buf, token.BlockNumber)
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(r.Failures)))
+	for _, failure := range r.Failures {
+		buf = append(buf, failure.Token[:]...)
+		msg := ""
+		if failure.Err != nil {
+			msg = failure.Err.Error()
+		}
+		buf = appendBinaryString(buf, msg)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler，读取 MarshalBinary 写出的数据
+func (r *QueryResult) UnmarshalBinary(data []byte) error {
+	d := &binaryDecoder{data: data}
+	version := d.byte()
+	if d.err == nil && (version < 1 || version > binaryFormatVersion) {
+		return fmt.Errorf("不支持的二进制格式版本%d", version)
+	}
+
+	var out QueryResult
+	out.SchemaVersion = int(d.uvarint())
+	out.QueryAddress = d.address()
+	out.ContractAddress = d.address()
+	if version >= 2 {
+		out.ENSName = d.string()
+	}
+	out.ReorgDetected = d.byte()&binaryReorgDetected != 0
+	out.Timestamp = d.big()
+	out.BlockNumber = d.big()
+	out.BaseFee = d.big()
+
+	if n := d.count(); n > 0 {
+		out.Tokens = make([]TokenInfo, n)
+		for i := range out.Tokens {
+			token := &out.Tokens[i]
+			token.TokenAddress = d.address()
+			token.Symbol = d.string()
+			token.Decimals = d.byte()
+			flags := d.byte()
+			token.DecimalsClamped = flags&binaryDecimalsClamped != 0
+			token.SuspiciousDecimals = flags&binarySuspiciousDecimals != 0
+			token.TransferBlocked = flags&binaryTransferBlocked != 0
+			token.EmptyBalanceData = flags&binaryEmptyBalanceData != 0
+			for source, flag := range binaryDecimalsSources {
+				if flags&flag != 0 {
+					token.DecimalsSource = source
+				}
+			}
+			token.Balance = d.big()
+			token.Shares = d.big()
+			token.TotalSupply = d.big()
+			token.BlockNumber = d.big()
+		}
+	}
+
+	if n := d.count(); n > 0 {
+		out.Failures = make([]TokenFailure, n)
+		for i := range out.Failures {
+			out.Failures[i].Token = d.address()
+			if msg := d.string(); msg != "" {
+				out.Failures[i].Err = errors.New(msg)
+			}
+		}
+	}
+
+	if d.err != nil {
+		return fmt.Errorf("解码二进制结果失败: %w", d.err)
+	}
+	if d.off != len(data) {
+		return fmt.Errorf("解码二进制结果失败: 末尾有%d字节多余数据", len(data)-d.off)
+	}
+	*r = out
+	return nil
+}
+
+// appendBinaryBig 写入大整数：uvarint(0)表示nil，否则为 uvarint((字节数+1)<<1|符号) 加上绝对值的大端字节
+func appendBinaryBig(buf []byte, x *big.Int) []byte {
+	if x == nil {
+		return binary.AppendUvarint(buf, 0)
+	}
+	magnitude := x.Bytes()
+	header := uint64(len(magnitude)+1) << 1
+	if x.Sign() < 0 {
+		header |= 1
+	}
+	buf = binary.AppendUvarint(buf, header)
+	return append(buf, magnitude...)
+}
+
+func appendBinaryString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// binaryDecoder 顺序读取二进制字段，出错后后续读取都返回零值，由调用方最后检查err
+type binaryDecoder struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (d *binaryDecoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.data)-d.off < n {
+		d.err = errBinaryTruncated
+		return nil
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *binaryDecoder) byte() byte {
+	if b := d.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *binaryDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data[d.off:])
+	if n <= 0 {
+		d.err = errBinaryTruncated
+		return 0
+	}
+	d.off += n
+	return v
+}
+
+// count 读取列表长度，长度不可能超过剩余字节数，避免损坏的数据导致巨大的内存分配
+func (d *binaryDecoder) count() int {
+	n := d.uvarint()
+	if d.err == nil && n > uint64(len(d.data)-d.off) {
+		d.err = errBinaryTruncated
+		return 0
+	}
+	return int(n)
+}
+
+func (d *binaryDecoder) address() common.Address {
+	return common.BytesToAddress(d.take(common.AddressLength))
+}
+
+func (d *binaryDecoder) string() string {
+	return string(d.take(d.count()))
+}
+
+func (d *binaryDecoder) big() *big.Int {
+	header := d.uvarint()
+	if header == 0 || d.err != nil {
+		return nil
+	}
+	if header>>1 > uint64(len(d.data)-d.off+1) {
+		d.err = errBinaryTruncated
+		return nil
+	}
+	x := new(big.Int).SetBytes(d.take(int(header>>1) - 1))
+	if header&1 == 1 {
+		x.Neg(x)
+	}
+	return x
+}
@@ -0,0 +1,85 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// binarySample 构造一个包含n个token的典型查询结果
+func binarySample(n int) *QueryResult {
+	result := &QueryResult{
+		QueryAddress:    common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		ContractAddress: fakeContract,
+		Timestamp:       big.NewInt(1700000000),
+		BlockNumber:     big.NewInt(18000000),
+		BaseFee:         big.NewInt(30e9),
+		Tokens:          make([]TokenInfo, n),
+	}
+	for i := range result.Tokens {
+		balance, _ := new(big.Int).SetString(fmt.Sprintf("%d000000000000000000", i+1), 10)
+		result.Tokens[i] = TokenInfo{
+			TokenAddress:   common.BigToAddress(big.NewInt(int64(0x10000 + i))),
+			Symbol:         fmt.Sprintf("T%d", i),
+			Decimals:       18,
+			Balance:        balance,
+			DecimalsSource: DecimalsSourceChain,
+		}
+	}
+	return result
+}
+
+// BenchmarkEncoding 比较 MarshalBinary 与 json.Marshal 的速度和体积，bytes 为编码后的字节数
+func BenchmarkEncoding(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		result := binarySample(n)
+		binaryData, err := result.MarshalBinary()
+		if err != nil {
+			b.Fatal(err)
+		}
+		jsonData, err := json.Marshal(result)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("binary/marshal/tokens=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := result.MarshalBinary(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(binaryData)), "bytes")
+		})
+		b.Run(fmt.Sprintf("json/marshal/tokens=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := json.Marshal(result); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(jsonData)), "bytes")
+		})
+		b.Run(fmt.Sprintf("binary/unmarshal/tokens=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var decoded QueryResult
+				if err := decoded.UnmarshalBinary(binaryData); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("json/unmarshal/tokens=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var decoded QueryResult
+				if err := json.Unmarshal(jsonData, &decoded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
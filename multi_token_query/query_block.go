@@ -0,0 +1,62 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// WithConfirmationDepth 查询"最新"数据时改为查询 链头-depth 的区块，获得抗重组的结果
+// 默认0即直接查询latest。目标区块需要仍在节点上可用，较小的depth（如几十个区块）在普通全节点上即可满足
+func WithConfirmationDepth(depth int) Option {
+	return func(c *MultiTokenQueryClient) {
+		if depth > 0 {
+			c.confirmationDepth = depth
+		}
+	}
+}
+
+// WithPendingState 查询"最新"数据时改为查询pending区块，使余额包含内存池中待打包交易的影响
+// 结果取决于节点看到的内存池，不同节点、不同时刻可能不同，不可复现；并非所有节点都支持pending状态的eth_call。
+// 启用后 WithConfirmationDepth 不再生效，默认查询已确认的latest区块
+func WithPendingState() Option {
+	return func(c *MultiTokenQueryClient) {
+		c.pending = true
+	}
+}
+
+// latestOpts 返回查询"最新"数据的 CallOpts，配置了确认深度时固定到 链头-depth，
+// ctx来自 Snapshot 时固定到快照区块
+func (c *MultiTokenQueryClient) latestOpts(ctx context.Context) (*bind.CallOpts, error) {
+	if block, ok := snapshotBlock(ctx); ok {
+		return &bind.CallOpts{Context: ctx, BlockNumber: block}, nil
+	}
+	opts := &bind.CallOpts{Context: ctx, Pending: c.pending}
+	if c.pending || c.confirmationDepth == 0 {
+		return opts, nil
+	}
+	return c.resolvePinnedOpts(opts)
+}
+
+// resolvePinnedOpts 未指定区块时通过 BlockNumber 解析当前区块（减去确认深度）并固定到opts上
+// pending查询没有可固定的区块，原样返回
+func (c *MultiTokenQueryClient) resolvePinnedOpts(opts *bind.CallOpts) (*bind.CallOpts, error) {
+	if opts.BlockNumber != nil || opts.Pending {
+		return opts, nil
+	}
+
+	ctx, stop := c.closeAware(opts.Context)
+	defer stop()
+	head, err := c.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询当前区块号失败: %w", err)
+	}
+
+	depth := uint64(c.confirmationDepth)
+	if head < depth {
+		return nil, fmt.Errorf("当前区块高度%d小于确认深度%d", head, depth)
+	}
+	return pinnedOpts(opts, new(big.Int).SetUint64(head-depth)), nil
+}
@@ -0,0 +1,98 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTimeBeforeGenesis 查询的时间早于链的创世区块
+	ErrTimeBeforeGenesis = errors.New("时间早于创世区块")
+	// ErrTimeInFuture 查询的时间晚于当前时间
+	ErrTimeInFuture = errors.New("时间晚于当前时间")
+)
+
+// maxBlockTimeCache 时间到区块号缓存的最大条目数，超过后清空重新缓存
+const maxBlockTimeCache = 4096
+
+// blockTimeCache 按Unix秒缓存 BlockNumberAtTime 的结果，并发安全
+type blockTimeCache struct {
+	mu     sync.Mutex
+	blocks map[int64]uint64
+}
+
+func (c *blockTimeCache) get(t int64) (*big.Int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.blocks[t]
+	if !ok {
+		return nil, false
+	}
+	return new(big.Int).SetUint64(n), true
+}
+
+func (c *blockTimeCache) put(t int64, n uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.blocks == nil || len(c.blocks) >= maxBlockTimeCache {
+		c.blocks = make(map[int64]uint64)
+	}
+	c.blocks[t] = n
+}
+
+// BlockNumberAtTime 返回时间戳不晚于t的最后一个区块号，即t时刻链上最新的区块
+//
+// 按区块头的时间戳二分查找，每一步一次 HeaderByNumber，在当前主网高度上约25次调用，与链的出块间隔无关。
+// 结果按秒缓存在客户端上，同一时间重复查询不再访问节点；t晚于链头时返回链头区块，这种结果会随新区块变化，不缓存。
+// t早于创世区块时返回的错误满足 errors.Is(err, ErrTimeBeforeGenesis)，晚于当前时间时满足 errors.Is(err, ErrTimeInFuture)
+func (c *MultiTokenQueryClient) BlockNumberAtTime(ctx context.Context, t time.Time) (*big.Int, error) {
+	if t.After(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrTimeInFuture, t.UTC().Format(time.RFC3339))
+	}
+	target := t.Unix()
+	if n, ok := c.blockTimes.get(target); ok {
+		return n, nil
+	}
+
+	ctx, stop := c.closeAware(ctx)
+	defer stop()
+	client := c.conn().client
+
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询最新区块头失败: %w", err)
+	}
+	if head.Time <= uint64(target) {
+		return head.Number, nil
+	}
+
+	genesis, err := client.HeaderByNumber(ctx, big.NewInt(0))
+	if err != nil {
+		return nil, fmt.Errorf("查询创世区块头失败: %w", err)
+	}
+	if uint64(target) < genesis.Time {
+		return nil, fmt.Errorf("%w: %s", ErrTimeBeforeGenesis, t.UTC().Format(time.RFC3339))
+	}
+
+	// 不变式：lo 的时间戳 <= target，hi 的时间戳 > target
+	lo, hi := uint64(0), head.Number.Uint64()
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return nil, fmt.Errorf("查询区块%d的区块头失败: %w", mid, err)
+		}
+		if header.Time <= uint64(target) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	c.blockTimes.put(target, lo)
+	return new(big.Int).SetUint64(lo), nil
+}
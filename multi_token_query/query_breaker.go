@@ -0,0 +1,98 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 节点连续失败，熔断器处于打开状态，调用被直接拒绝
+var ErrCircuitOpen = errors.New("节点熔断中，暂停请求")
+
+// WithCircuitBreaker 为每个节点连接启用熔断器：连续threshold次调用失败后熔断，
+// cooldown 时间内发往该节点的调用直接返回 ErrCircuitOpen，不再占用重试和请求配额；
+// 冷却结束后放行一次试探调用，成功即恢复，失败则重新熔断。
+//
+// 使用连接池（NewPooledMultiTokenQueryClient）时，熔断中的节点会被跳过，调用转到其他正常的节点，
+// 所有节点都熔断时才返回 ErrCircuitOpen。合约revert、响应超出大小限制和缺少历史状态说明节点正常响应了请求，
+// 调用方取消或到期也不算节点失败；调用方的ctx仍有效而单次调用超时（Config.CallTimeout、WithScaledTimeout）算作失败。
+// ErrCircuitOpen 不会被 WithRetry 重试
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *MultiTokenQueryClient) {
+		if threshold < 1 {
+			threshold = 1
+		}
+		c.breakerThreshold = threshold
+		c.breakerCooldown = cooldown
+	}
+}
+
+// circuitBreaker 单个节点的熔断器
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	// probing 冷却结束后已放行一次试探调用，结果返回前不再放行其他调用
+	probing bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow 判断是否放行一次调用
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record 记录一次调用的结果，ctx为本次调用使用的ctx
+func (b *circuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 调用方取消或到期与节点健康无关，只释放试探名额
+	if (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) && callerDone(ctx) {
+		b.probing = false
+		return
+	}
+	// revert、超出大小限制和缺少历史状态都说明节点正常处理了请求
+	if err == nil || errors.Is(err, ErrRevert) || isSizeLimitError(err) || isHistoricalStateError(err) {
+		b.open, b.probing, b.failures = false, false, 0
+		return
+	}
+
+	b.failures++
+	if b.probing || b.failures >= b.threshold {
+		b.open, b.probing = true, false
+		b.openedAt = time.Now()
+	}
+}
+
+// availableConn 轮询选出一个未熔断的连接，全部熔断时返回 ErrCircuitOpen
+func (c *MultiTokenQueryClient) availableConn() (*poolConn, error) {
+	if c.breakerThreshold == 0 {
+		return c.conn(), nil
+	}
+	for range c.conns {
+		conn := c.conn()
+		if conn.breaker.allow() {
+			return conn, nil
+		}
+	}
+	return nil, ErrCircuitOpen
+}
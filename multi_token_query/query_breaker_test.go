@@ -0,0 +1,53 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerRecord(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	perCall, cancelCall := withCallTimeout(context.Background(), -time.Second)
+	defer cancelCall()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		wantOpen bool
+	}{
+		{name: "连接错误", ctx: context.Background(), err: errors.New("connection refused"), wantOpen: true},
+		{name: "revert", ctx: context.Background(), err: ErrRevert},
+		{name: "响应超出大小限制", ctx: context.Background(), err: &fakeRPCError{code: -32000, msg: "response size exceeded"}},
+		{name: "缺少历史状态", ctx: context.Background(), err: &fakeRPCError{code: -32000, msg: "missing trie node abc (path )"}},
+		{name: "调用方ctx有效时超时", ctx: context.Background(), err: context.DeadlineExceeded, wantOpen: true},
+		{name: "单次调用超时", ctx: perCall, err: context.DeadlineExceeded, wantOpen: true},
+		{name: "调用方ctx到期", ctx: expired, err: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(2, time.Minute)
+			for i := 0; i < 2; i++ {
+				b.record(tt.ctx, tt.err)
+			}
+			if open := !b.allow(); open != tt.wantOpen {
+				t.Fatalf("熔断 = %v，期望 %v", open, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHealthyResponseResetsFailures(t *testing.T) {
+	b := newCircuitBreaker(2, time.Minute)
+	ctx := context.Background()
+	b.record(ctx, errors.New("connection refused"))
+	b.record(ctx, &fakeRPCError{code: -32000, msg: "state not available"})
+	b.record(ctx, errors.New("connection refused"))
+	if !b.allow() {
+		t.Fatal("缺少历史状态的响应应清零连续失败次数")
+	}
+}
@@ -0,0 +1,205 @@
+package contracts
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// minCacheSweep 结果缓存清理过期条目的最小条目数
+const minCacheSweep = 1024
+
+// WithResultCache 按（用户，token）缓存 QueryMultipleTokens 的结果，ttl内重复查询同一token直接使用缓存，
+// 只有过期或未缓存的token才查询链上，与缓存的token合并后返回
+//
+// 来自缓存的token会填充 TokenInfo.BlockNumber 为它被查询时的区块，结果的区块号为本次实际查询的区块
+// （全部命中缓存时为缓存中最新的区块），同一结果中的token可能来自不同区块。
+// 只缓存查询最新数据的调用：pending模式、Snapshot 固定区块的ctx以及按区块查询的方法都不使用缓存。
+// 各token的缓存时间可用 WithTokenTTL 单独设置
+func WithResultCache(ttl time.Duration) Option {
+	return func(c *MultiTokenQueryClient) {
+		if ttl > 0 {
+			c.ensureResultCache().ttl = ttl
+		}
+	}
+}
+
+// WithTokenTTL 按token覆盖 WithResultCache 的缓存时间，未列出的token使用全局ttl，ttl<=0 表示该token不缓存
+// 例如冷钱包的稳定币余额很少变化，可以缓存更久：
+//
+//	ttls := make(map[common.Address]time.Duration)
+//	for token := range contracts.DefaultStablecoins {
+//		ttls[token] = 10 * time.Minute
+//	}
+//	contracts.WithResultCache(30*time.Second), contracts.WithTokenTTL(ttls)
+//
+// 只配置 WithTokenTTL 而没有 WithResultCache 时只缓存列出的token
+func WithTokenTTL(ttls map[common.Address]time.Duration) Option {
+	return func(c *MultiTokenQueryClient) {
+		cache := c.ensureResultCache()
+		for token, ttl := range ttls {
+			cache.tokenTTL[token] = ttl
+		}
+	}
+}
+
+// ClearResultCache 清空结果缓存，例如用户发起转账后需要立即看到最新余额时
+func (c *MultiTokenQueryClient) ClearResultCache() {
+	if c.cache == nil {
+		return
+	}
+	c.cache.mu.Lock()
+	c.cache.entries = make(map[cacheKey]cachedToken)
+	c.cache.mu.Unlock()
+}
+
+func (c *MultiTokenQueryClient) ensureResultCache() *resultCache {
+	if c.cache == nil {
+		c.cache = &resultCache{
+			tokenTTL:  make(map[common.Address]time.Duration),
+			entries:   make(map[cacheKey]cachedToken),
+			nextSweep: minCacheSweep,
+		}
+	}
+	return c.cache
+}
+
+// resultCache 按（用户，token）缓存的查询结果，并发安全
+type resultCache struct {
+	ttl      time.Duration
+	tokenTTL map[common.Address]time.Duration
+
+	mu        sync.Mutex
+	entries   map[cacheKey]cachedToken
+	nextSweep int
+}
+
+type cacheKey struct {
+	user, token common.Address
+}
+
+// cachedToken 缓存的单个token及其所在结果的区块信息
+type cachedToken struct {
+	info        TokenInfo
+	blockNumber *big.Int
+	timestamp   *big.Int
+	contract    common.Address
+	expires     time.Time
+}
+
+// ttlFor 返回token的缓存时间
+func (rc *resultCache) ttlFor(token common.Address) time.Duration {
+	if ttl, ok := rc.tokenTTL[token]; ok {
+		return ttl
+	}
+	return rc.ttl
+}
+
+// lookup 返回tokens中未过期的缓存条目和需要查询的token
+func (rc *resultCache) lookup(user common.Address, tokens []common.Address) (map[common.Address]cachedToken, []common.Address) {
+	now := time.Now()
+	hits := make(map[common.Address]cachedToken)
+	var misses []common.Address
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, token := range tokens {
+		key := cacheKey{user, token}
+		entry, ok := rc.entries[key]
+		if ok && now.Before(entry.expires) {
+			hits[token] = entry
+			continue
+		}
+		if ok {
+			delete(rc.entries, key)
+		}
+		misses = append(misses, token)
+	}
+	return hits, misses
+}
+
+// store 缓存result中的token，缓存时间<=0的token不缓存
+func (rc *resultCache) store(result *QueryResult) {
+	now := time.Now()
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, token := range result.Tokens {
+		ttl := rc.ttlFor(token.TokenAddress)
+		if ttl <= 0 {
+			continue
+		}
+		rc.entries[cacheKey{result.QueryAddress, token.TokenAddress}] = cachedToken{
+			info:        cloneTokenInfo(token),
+			blockNumber: result.BlockNumber,
+			timestamp:   result.Timestamp,
+			contract:    result.ContractAddress,
+			expires:     now.Add(ttl),
+		}
+	}
+
+	// 条目数翻倍时清理一次过期条目，避免不再查询的token一直占用内存
+	if len(rc.entries) >= rc.nextSweep {
+		for key, entry := range rc.entries {
+			if !now.Before(entry.expires) {
+				delete(rc.entries, key)
+			}
+		}
+		rc.nextSweep = max(2*len(rc.entries), minCacheSweep)
+	}
+}
+
+// merge 按tokens的顺序合并本次查询的结果fresh（可为nil）和缓存命中的token
+func (rc *resultCache) merge(user common.Address, tokens []common.Address, fresh *QueryResult, hits map[common.Address]cachedToken) *QueryResult {
+	merged := &QueryResult{QueryAddress: user, Tokens: make([]TokenInfo, 0, len(tokens))}
+	freshTokens := make(map[common.Address]TokenInfo)
+	if fresh != nil {
+		merged.ContractAddress = fresh.ContractAddress
+		merged.ENSName = fresh.ENSName
+		merged.Timestamp = fresh.Timestamp
+		merged.BlockNumber = fresh.BlockNumber
+		merged.BaseFee = fresh.BaseFee
+		merged.ReorgDetected = fresh.ReorgDetected
+		merged.Failures = fresh.Failures
+		for _, token := range fresh.Tokens {
+			freshTokens[token.TokenAddress] = token
+		}
+	}
+
+	for _, addr := range tokens {
+		if token, ok := freshTokens[addr]; ok {
+			merged.Tokens = append(merged.Tokens, token)
+			continue
+		}
+		entry, ok := hits[addr]
+		if !ok {
+			continue
+		}
+		token := cloneTokenInfo(entry.info)
+		token.BlockNumber = entry.blockNumber
+		merged.Tokens = append(merged.Tokens, token)
+
+		if fresh == nil && (merged.BlockNumber == nil || (entry.blockNumber != nil && entry.blockNumber.Cmp(merged.BlockNumber) > 0)) {
+			merged.BlockNumber, merged.Timestamp, merged.ContractAddress = entry.blockNumber, entry.timestamp, entry.contract
+		}
+	}
+	return merged
+}
+
+// cloneTokenInfo 复制token信息中的大整数，避免调用方修改结果时影响缓存
+func cloneTokenInfo(t TokenInfo) TokenInfo {
+	t.Balance = cloneBig(t.Balance)
+	t.Shares = cloneBig(t.Shares)
+	t.TotalSupply = cloneBig(t.TotalSupply)
+	t.BlockNumber = cloneBig(t.BlockNumber)
+	return t
+}
+
+func cloneBig(x *big.Int) *big.Int {
+	if x == nil {
+		return nil
+	}
+	return new(big.Int).Set(x)
+}
@@ -0,0 +1,64 @@
+package contracts
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// serveENSName 让假节点上的ENS注册表和解析器把user反向解析为name，name也正向解析回user
+func serveENSName(user common.Address, name string) func(to common.Address, data []byte) ([]byte, error) {
+	resolver := common.HexToAddress("0x00000000000000000000000000000000000e4500")
+	return func(to common.Address, data []byte) ([]byte, error) {
+		switch {
+		case to == DefaultENSRegistry && bytes.Equal(data[:4], selectorENSResolver):
+			return common.LeftPadBytes(resolver.Bytes(), 32), nil
+		case to == resolver && bytes.Equal(data[:4], selectorENSName):
+			stringType, _ := abi.NewType("string", "", nil)
+			return abi.Arguments{{Type: stringType}}.Pack(name)
+		case to == resolver && bytes.Equal(data[:4], selectorENSAddr):
+			return common.LeftPadBytes(user.Bytes(), 32), nil
+		}
+		return nil, nil
+	}
+}
+
+func TestResultCacheKeepsENSName(t *testing.T) {
+	client, node := newFakeClient(t, WithENSNames(), WithResultCache(time.Minute))
+	tokens := node.addTokens(2)
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	node.setHook(serveENSName(user, "alice.eth"))
+	ctx := context.Background()
+
+	first, err := client.QueryMultipleTokens(ctx, user, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ENSName != "alice.eth" {
+		t.Fatalf("首次查询 ENSName = %q", first.ENSName)
+	}
+
+	calls := node.calls.Load()
+	cached, err := client.QueryMultipleTokens(ctx, user, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := node.calls.Load() - calls; n != 0 {
+		t.Errorf("全部命中缓存时仍发出了%d次eth_call", n)
+	}
+	if cached.ENSName != "alice.eth" {
+		t.Errorf("全部命中缓存时 ENSName = %q，期望 alice.eth", cached.ENSName)
+	}
+
+	partial, err := client.QueryMultipleTokens(ctx, user, append(tokens, node.addTokens(1)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partial.ENSName != "alice.eth" || len(partial.Tokens) != 3 {
+		t.Errorf("部分命中缓存时 ENSName = %q，token数 %d", partial.ENSName, len(partial.Tokens))
+	}
+}
@@ -0,0 +1,69 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultCategory 没有分类标签的钱包归入的分类
+const DefaultCategory = "untagged"
+
+// CategoryReport 一个钱包分类（如 hot、cold、treasury）的查询结果
+type CategoryReport struct {
+	Category string
+	// Results 该分类下查询成功的钱包结果，按传入users中的顺序排列
+	Results []*QueryResult
+	// TotalUSD 该分类所有钱包持仓的美元总价值，不含没有价格的token
+	TotalUSD *big.Float
+	// Unpriced 预言机没有价格、未计入总价值的token，每个token只出现一次
+	Unpriced []common.Address
+}
+
+// QueryByCategory 批量查询users，并按categories中的分类标签分组汇总，用于资金报表
+//
+// categories 中没有的钱包归入 DefaultCategory。查询与 QueryMultipleTokensBatch 一样并发执行，
+// 单个钱包失败不影响其他钱包：失败的钱包不出现在报告中，所有失败通过 errors.Join 合并返回，
+// 此时返回的报告仍然有效。价格查询出错（ErrPriceUnavailable 以外的错误）时返回nil和该错误
+func (c *MultiTokenQueryClient) QueryByCategory(ctx context.Context, users []common.Address, tokenAddresses []common.Address, categories map[common.Address]string, oracle PriceOracle) (_ map[string]*CategoryReport, err error) {
+	ctx, end := c.startSpan(ctx, "QueryByCategory", common.Address{}, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(nil, err) }()
+
+	results, errs := c.queryBatch(ctx, users, tokenAddresses)
+
+	reports := make(map[string]*CategoryReport)
+	unpriced := make(map[string]map[common.Address]bool)
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		category, ok := categories[users[i]]
+		if !ok || category == "" {
+			category = DefaultCategory
+		}
+
+		report, ok := reports[category]
+		if !ok {
+			report = &CategoryReport{Category: category, TotalUSD: new(big.Float)}
+			reports[category] = report
+			unpriced[category] = make(map[common.Address]bool)
+		}
+		report.Results = append(report.Results, result)
+
+		value, missing, err := result.ValueUSD(ctx, oracle)
+		if err != nil {
+			return nil, err
+		}
+		report.TotalUSD.Add(report.TotalUSD, value)
+		for _, token := range missing {
+			if !unpriced[category][token] {
+				unpriced[category][token] = true
+				report.Unpriced = append(report.Unpriced, token)
+			}
+		}
+	}
+
+	return reports, errors.Join(errs...)
+}
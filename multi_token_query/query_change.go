@@ -0,0 +1,43 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Query24hChange 查询当前余额和约24小时前的余额，调用方可据此计算涨跌幅
+//
+// 24小时前的区块按当前结果的区块时间戳减去24小时，通过 BlockNumberAtTime 得到（取不晚于该时间的最后一个区块），
+// 不依赖固定的出块间隔，适用于各种出块速度的链。历史查询需要归档节点，节点不支持时返回的错误满足
+// errors.Is(err, ErrHistoricalUnsupported)
+func (c *MultiTokenQueryClient) Query24hChange(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) (current *QueryResult, prior *QueryResult, err error) {
+	ctx, end := c.startSpan(ctx, "Query24hChange", userAddress, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(resultBlock(current), err) }()
+
+	current, err = c.QueryMultipleTokens(ctx, userAddress, tokenAddresses)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now, ok := timestampToTime(current.Timestamp)
+	if !ok {
+		return nil, nil, fmt.Errorf("当前结果的区块时间戳无效: %v", current.Timestamp)
+	}
+	block, err := c.BlockNumberAtTime(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		return nil, nil, fmt.Errorf("查找24小时前的区块失败: %w", err)
+	}
+
+	prior, err = c.queryResultAt(&bind.CallOpts{Context: ctx, BlockNumber: block}, userAddress, tokenAddresses)
+	if err != nil {
+		if isHistoricalStateError(err) {
+			return nil, nil, fmt.Errorf("%w: 区块%v: %v", ErrHistoricalUnsupported, block, err)
+		}
+		return nil, nil, fmt.Errorf("查询区块%v的余额失败: %w", block, err)
+	}
+	return current, prior, nil
+}
@@ -0,0 +1,143 @@
+package contracts
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ReorgPolicy 分批查询时各批次区块号不一致的处理方式
+type ReorgPolicy int
+
+const (
+	// ReorgRequery 把区块号不一致的批次固定在第一个批次的区块上重新查询（默认）
+	ReorgRequery ReorgPolicy = iota
+	// ReorgWarn 不重新查询，在 QueryResult.ReorgDetected 上标记
+	ReorgWarn
+)
+
+// WithChunkSize 每次合约调用最多查询n个token，超出时拆成多次调用后合并
+// 用于规避节点对单次 eth_call 的gas或返回大小限制，n<=0 表示不拆分
+func WithChunkSize(n int) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.chunkSize = n
+	}
+}
+
+// WithReorgPolicy 设置分批查询时批次落在不同区块上的处理方式
+// 只影响 QueryMultipleTokens；QueryBalances 没有结果结构可以标记，总是重新查询
+func WithReorgPolicy(policy ReorgPolicy) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.reorgPolicy = policy
+	}
+}
+
+// chunkTokens 把token列表按size拆分，size<=0 时不拆分
+func chunkTokens(tokens []common.Address, size int) [][]common.Address {
+	if size <= 0 || len(tokens) <= size {
+		return [][]common.Address{tokens}
+	}
+
+	chunks := make([][]common.Address, 0, (len(tokens)+size-1)/size)
+	for start := 0; start < len(tokens); start += size {
+		end := start + size
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		chunks = append(chunks, tokens[start:end])
+	}
+	return chunks
+}
+
+// pinnedOpts 复制opts并固定到指定区块
+func pinnedOpts(opts *bind.CallOpts, blockNumber *big.Int) *bind.CallOpts {
+	pinned := *opts
+	pinned.BlockNumber = blockNumber
+	return &pinned
+}
+
+// queryMultipleTokens 按 WithChunkSize 拆分调用并合并结果
+func (c *MultiTokenQueryClient) queryMultipleTokens(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+	chunks := chunkTokens(tokenAddresses, c.effectiveChunkSize())
+	if len(chunks) == 1 {
+		return c.queryMultipleTokensChunk(opts, userAddress, tokenAddresses)
+	}
+
+	results := make([]*QueryResult, len(chunks))
+	err := c.runChunks(opts, len(chunks), func(opts *bind.CallOpts, i int) (*big.Int, error) {
+		result, err := c.queryMultipleTokensChunk(opts, userAddress, chunks[i])
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+		return result.BlockNumber, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &QueryResult{
+		QueryAddress: userAddress,
+		Tokens:       make([]TokenInfo, 0, len(tokenAddresses)),
+		Timestamp:    results[0].Timestamp,
+		BlockNumber:  results[0].BlockNumber,
+	}
+	for i, result := range results {
+		if result.BlockNumber != nil && merged.BlockNumber != nil && result.BlockNumber.Cmp(merged.BlockNumber) != 0 {
+			if c.reorgPolicy == ReorgWarn {
+				merged.ReorgDetected = true
+			} else {
+				requeried, err := c.queryMultipleTokensChunk(pinnedOpts(opts, merged.BlockNumber), userAddress, chunks[i])
+				if err != nil {
+					return nil, err
+				}
+				result = requeried
+			}
+		}
+		merged.Tokens = append(merged.Tokens, result.Tokens...)
+		merged.Failures = append(merged.Failures, result.Failures...)
+	}
+
+	return merged, nil
+}
+
+// queryBalances 按 WithChunkSize 拆分调用并合并结果，批次区块号不一致时固定到第一个批次的区块重新查询
+func (c *MultiTokenQueryClient) queryBalances(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address) ([]*big.Int, *big.Int, *big.Int, error) {
+	chunks := chunkTokens(tokenAddresses, c.effectiveChunkSize())
+	if len(chunks) == 1 {
+		return c.queryBalancesOnce(opts, userAddress, tokenAddresses)
+	}
+
+	chunkBalances := make([][]*big.Int, len(chunks))
+	chunkBlocks := make([]*big.Int, len(chunks))
+	var timestamp *big.Int
+	err := c.runChunks(opts, len(chunks), func(opts *bind.CallOpts, i int) (*big.Int, error) {
+		balances, chunkTimestamp, chunkBlock, err := c.queryBalancesOnce(opts, userAddress, chunks[i])
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			timestamp = chunkTimestamp
+		}
+		chunkBalances[i], chunkBlocks[i] = balances, chunkBlock
+		return chunkBlock, nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	blockNumber := chunkBlocks[0]
+	var balances []*big.Int
+	for i, chunk := range chunks {
+		if chunkBlocks[i].Cmp(blockNumber) != 0 {
+			chunkBalances[i], _, _, err = c.queryBalancesOnce(pinnedOpts(opts, blockNumber), userAddress, chunk)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		balances = append(balances, chunkBalances[i]...)
+	}
+
+	return balances, timestamp, blockNumber, nil
+}
@@ -0,0 +1,55 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCloseUnblocksInFlightCalls(t *testing.T) {
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	tests := []struct {
+		name string
+		call func(c *MultiTokenQueryClient, tokens []common.Address) error
+	}{
+		{
+			name: "合约调用",
+			call: func(c *MultiTokenQueryClient, tokens []common.Address) error {
+				_, err := c.QueryMultipleTokens(context.Background(), user, tokens)
+				return err
+			},
+		},
+		{
+			name: "直接调用token合约",
+			call: func(c *MultiTokenQueryClient, tokens []common.Address) error {
+				_, err := c.FilterERC20(context.Background(), tokens)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, node := newFakeClient(t)
+			tokens := node.addTokens(2)
+			node.setLatency(time.Minute)
+
+			done := make(chan error, 1)
+			go func() { done <- tt.call(client, tokens) }()
+			time.Sleep(50 * time.Millisecond)
+			client.Close()
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, ErrClientClosed) && !errors.Is(err, context.Canceled) {
+					t.Fatalf("错误 = %v，期望 ErrClientClosed 或 context.Canceled", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Close 之后调用没有返回")
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Config 客户端配置，可直接从YAML/JSON解码，通过 NewMultiTokenQueryClientFromConfig 创建客户端
+// 零值字段表示使用默认行为
+//
+// 时长字段（CallTimeout 以及 Retry 中的等待时间）类型为 time.Duration：
+// 用 gopkg.in/yaml.v3 解码时可以写成 "5s"、"1m30s"；encoding/json 只接受整数纳秒（如 5000000000），不接受 "5s"
+type Config struct {
+	// RPCURLs 节点地址，至少一个；多个地址时按顺序轮流建立连接
+	RPCURLs []string `json:"rpcUrls" yaml:"rpcUrls"`
+	// ContractAddress 查询合约地址，0x开头的十六进制
+	ContractAddress string `json:"contractAddress" yaml:"contractAddress"`
+	// ABI 自定义合约ABI（JSON），为空时使用内置ABI
+	// 必须包含与内置ABI同名、同结构的 queryMultipleTokens、queryBalances、querySingleToken 方法，
+	// 适用于部署了重新编译（如调整了参数名）的合约；可用 VerifyABI 确认与部署的合约一致
+	ABI string `json:"abi,omitempty" yaml:"abi,omitempty"`
+	// ChainID 期望的链ID，非0时创建客户端时检查节点的链ID，不一致时创建失败，避免误连到其他网络
+	ChainID uint64 `json:"chainId,omitempty" yaml:"chainId,omitempty"`
+	// CallTimeout 单次合约调用的超时（包括方法层重试的全部尝试），0表示不设超时；调用方的ctx更早到期时以ctx为准
+	CallTimeout time.Duration `json:"callTimeout,omitempty" yaml:"callTimeout,omitempty"`
+	// Retry 方法层重试策略，为nil时不重试，见 WithRetry
+	Retry *RetryPolicy `json:"retry,omitempty" yaml:"retry,omitempty"`
+	// RateLimit 每秒最多发起的合约调用次数，0表示不限制
+	// 每次尝试（包括方法层重试）都计入限额，超出时等待而不是报错，适合有请求配额的节点服务商
+	RateLimit float64 `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	// RateBurst 限流允许的瞬时突发数，0时按1处理
+	RateBurst int `json:"rateBurst,omitempty" yaml:"rateBurst,omitempty"`
+}
+
+// Validate 检查配置是否有效，返回所有问题合并后的错误
+func (cfg Config) Validate() error {
+	var errs []error
+
+	if len(cfg.RPCURLs) == 0 {
+		errs = append(errs, errors.New("至少需要一个节点地址"))
+	}
+	for i, u := range cfg.RPCURLs {
+		if strings.TrimSpace(u) == "" {
+			errs = append(errs, fmt.Errorf("第%d个节点地址为空", i))
+		}
+	}
+	if !common.IsHexAddress(cfg.ContractAddress) {
+		errs = append(errs, fmt.Errorf("合约地址无效: %q", cfg.ContractAddress))
+	} else if common.HexToAddress(cfg.ContractAddress) == (common.Address{}) {
+		errs = append(errs, errors.New("合约地址不能为零地址"))
+	}
+	if cfg.ABI != "" {
+		if _, err := abi.JSON(strings.NewReader(cfg.ABI)); err != nil {
+			errs = append(errs, fmt.Errorf("解析合约ABI失败: %v", err))
+		}
+	}
+	if cfg.CallTimeout < 0 {
+		errs = append(errs, fmt.Errorf("调用超时不能为负数: %s", cfg.CallTimeout))
+	}
+	if cfg.Retry != nil {
+		if cfg.Retry.MaxAttempts < 0 {
+			errs = append(errs, fmt.Errorf("重试次数不能为负数: %d", cfg.Retry.MaxAttempts))
+		}
+		if cfg.Retry.InitialBackoff < 0 || cfg.Retry.MaxBackoff < 0 {
+			errs = append(errs, errors.New("重试等待时间不能为负数"))
+		}
+	}
+	if cfg.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("限流速率不能为负数: %v", cfg.RateLimit))
+	}
+	if cfg.RateBurst < 0 {
+		errs = append(errs, fmt.Errorf("限流突发数不能为负数: %d", cfg.RateBurst))
+	}
+
+	return errors.Join(errs...)
+}
+
+// apply 把配置写入客户端，作为第一个 Option 应用
+func (cfg Config) apply(c *MultiTokenQueryClient) {
+	if cfg.ABI != "" {
+		c.abiJSON = cfg.ABI
+	}
+	if cfg.ChainID != 0 {
+		c.chainID = new(big.Int).SetUint64(cfg.ChainID)
+	}
+	c.callTimeout = cfg.CallTimeout
+	if cfg.Retry != nil {
+		policy := *cfg.Retry
+		c.retry = &policy
+	}
+	if cfg.RateLimit > 0 {
+		c.limiter = newRateLimiter(cfg.RateLimit, cfg.RateBurst)
+	}
+}
+
+// NewMultiTokenQueryClientFromConfig 校验配置并创建客户端，opts 在配置之后应用，可覆盖配置中的项
+func NewMultiTokenQueryClientFromConfig(cfg Config, opts ...Option) (*MultiTokenQueryClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("配置无效: %w", err)
+	}
+	return newMultiTokenQueryClient(cfg.RPCURLs, common.HexToAddress(cfg.ContractAddress), append([]Option{cfg.apply}, opts...))
+}
+
+// ConfigFromEnv 从环境变量读取配置并校验，结果可直接传给 NewMultiTokenQueryClientFromConfig
+//
+//	RPC_URL           节点地址，必填；多个地址用逗号分隔
+//	CONTRACT_ADDRESS  查询合约地址，必填
+//	CHAIN_ID          期望的链ID（十进制），可选
+//	TIMEOUT           单次合约调用的超时，可选；Go duration格式（如 10s、1m30s）或整数秒
+func ConfigFromEnv() (Config, error) {
+	var cfg Config
+	var errs []error
+
+	rpcURL := strings.TrimSpace(os.Getenv("RPC_URL"))
+	if rpcURL == "" {
+		errs = append(errs, errors.New("缺少环境变量RPC_URL"))
+	} else {
+		for _, u := range strings.Split(rpcURL, ",") {
+			cfg.RPCURLs = append(cfg.RPCURLs, strings.TrimSpace(u))
+		}
+	}
+
+	cfg.ContractAddress = strings.TrimSpace(os.Getenv("CONTRACT_ADDRESS"))
+	if cfg.ContractAddress == "" {
+		errs = append(errs, errors.New("缺少环境变量CONTRACT_ADDRESS"))
+	}
+
+	if v := strings.TrimSpace(os.Getenv("CHAIN_ID")); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("环境变量CHAIN_ID格式错误: %q", v))
+		}
+		cfg.ChainID = id
+	}
+
+	if v := strings.TrimSpace(os.Getenv("TIMEOUT")); v != "" {
+		timeout, err := parseTimeout(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("环境变量TIMEOUT格式错误: %q", v))
+		}
+		cfg.CallTimeout = timeout
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// parseTimeout 解析Go duration或整数秒
+func parseTimeout(v string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(v)
+}
@@ -0,0 +1,39 @@
+package contracts
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestConfigJSONDurations(t *testing.T) {
+	var cfg Config
+	data := `{"rpcUrls":["http://localhost:8545"],"contractAddress":"0x00000000000000000000000000000000000c0de0","callTimeout":5000000000,"retry":{"maxAttempts":3,"initialBackoff":100000000}}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CallTimeout != 5*time.Second || cfg.Retry.InitialBackoff != 100*time.Millisecond {
+		t.Fatalf("CallTimeout = %s, InitialBackoff = %s", cfg.CallTimeout, cfg.Retry.InitialBackoff)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"callTimeout":"5s"}`), &cfg); err == nil {
+		t.Fatal(`encoding/json 不应接受 "5s"，文档说明需要整数纳秒`)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := Config{RPCURLs: []string{""}, ContractAddress: "0x0", CallTimeout: -time.Second, RateLimit: -1}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("期望返回错误")
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	client, _ := newFakeClient(t, Config{ChainID: 1, CallTimeout: time.Second, RateLimit: 50, RateBurst: 2}.apply)
+	if client.callTimeout != time.Second || client.limiter == nil || client.chainID.Uint64() != 1 {
+		t.Fatalf("配置没有应用: callTimeout=%s limiter=%v chainID=%v", client.callTimeout, client.limiter, client.chainID)
+	}
+}
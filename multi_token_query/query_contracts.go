@@ -0,0 +1,133 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WithFallbackContracts 配置备用的查询合约地址
+// 主合约调用失败（revert或其他错误）时按顺序尝试备用合约，用于多地址冗余部署或合约迁移期间。
+// 这是合约层面的冗余，与节点故障转移无关；所有合约必须与客户端配置的ABI一致
+func WithFallbackContracts(addrs ...common.Address) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.fallbackContracts = append(c.fallbackContracts, addrs...)
+	}
+}
+
+// ActiveContract 返回最近一次成功调用所使用的合约地址，尚未成功调用过时返回主合约地址
+func (c *MultiTokenQueryClient) ActiveContract() common.Address {
+	return c.contractAddrs[c.activeContract.Load()]
+}
+
+// invoke 发起一次合约调用，主合约失败时依次尝试备用合约
+// 全部失败时返回主合约的错误
+// 配置了 WithReadReplicas 时先在副本上调用，副本故障时再走主节点
+func (c *MultiTokenQueryClient) invoke(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+	if replica := c.availableReplica(); replica != nil {
+		err := c.invokeOn(replica, opts, results, method, params...)
+		if replica.breaker != nil {
+			replica.breaker.record(opts.Context, err)
+		}
+		if !replicaFailover(opts.Context, err) {
+			return err
+		}
+		c.logger.Debug("只读副本调用失败，改用主节点", "method", method, "err", err)
+
+		// 副本耗尽了单次调用超时，主节点重新计时
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			ctx, cancel := renewCallTimeout(opts.Context)
+			defer cancel()
+			bound := *opts
+			bound.Context = ctx
+			opts = &bound
+		}
+	}
+
+	conn, err := c.availableConn()
+	if err != nil {
+		return err
+	}
+	err = c.invokeOn(conn, opts, results, method, params...)
+	if conn.breaker != nil {
+		conn.breaker.record(opts.Context, err)
+	}
+	return err
+}
+
+// invokeOn 在conn上依次尝试主合约和备用合约
+func (c *MultiTokenQueryClient) invokeOn(conn *poolConn, opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+	var firstErr error
+	for i := range c.contractAddrs {
+		*results = (*results)[:0]
+		err := c.invokeContract(conn, i, opts, results, method, params...)
+		if err == nil {
+			c.activeContract.Store(int32(i))
+			if used, ok := opts.Context.Value(contractUsedKey{}).(*contractUsed); ok {
+				used.set(c.contractAddrs[i])
+			}
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+		if i+1 < len(c.contractAddrs) {
+			c.logger.Debug("合约调用失败，尝试备用合约", "method", method, "contract", c.contractAddrs[i].Hex(), "err", err)
+		}
+	}
+	return firstErr
+}
+
+// contractUsedKey 在ctx中记录一次查询实际使用的合约地址
+type contractUsedKey struct{}
+
+// contractUsed 分批查询的各批次可能并发执行，用锁保护
+type contractUsed struct {
+	mu   sync.Mutex
+	addr common.Address
+}
+
+func (u *contractUsed) set(addr common.Address) {
+	u.mu.Lock()
+	u.addr = addr
+	u.mu.Unlock()
+}
+
+func (u *contractUsed) get() common.Address {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.addr
+}
+
+// withContractCapture 复制opts，使通过它发起的调用记录实际使用的合约地址
+func withContractCapture(opts *bind.CallOpts) *bind.CallOpts {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	captured := *opts
+	captured.Context = context.WithValue(ctx, contractUsedKey{}, &contractUsed{})
+	return &captured
+}
+
+// fillContractAddress 填充结果的 ContractAddress，没有记录时使用最近一次成功调用的合约
+func (c *MultiTokenQueryClient) fillContractAddress(opts *bind.CallOpts, result *QueryResult) {
+	if result.ContractAddress != (common.Address{}) {
+		return
+	}
+	if opts.Context != nil {
+		if used, ok := opts.Context.Value(contractUsedKey{}).(*contractUsed); ok {
+			if addr := used.get(); addr != (common.Address{}) {
+				result.ContractAddress = addr
+				return
+			}
+		}
+	}
+	result.ContractAddress = c.ActiveContract()
+}
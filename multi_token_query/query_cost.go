@@ -0,0 +1,138 @@
+package contracts
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// CostModel 各JSON-RPC方法的估算费用，单位为服务商的计费单位（Alchemy的CU、Infura的credit等）
+type CostModel struct {
+	// Methods 方法名到单次调用费用的映射
+	Methods map[string]int64
+	// Default Methods 中没有列出的方法的费用
+	Default int64
+}
+
+// Cost 返回一次method调用的估算费用
+func (m CostModel) Cost(method string) int64 {
+	if units, ok := m.Methods[method]; ok {
+		return units
+	}
+	return m.Default
+}
+
+// CostModels 各服务商档位的费用表，取自服务商公开的价目（2024年），只覆盖客户端会用到的方法。
+// 服务商会调整价目，出入较大时可直接修改该表，或向 WithCostModel 传入自己的 CostModel
+var CostModels = map[ProviderTier]CostModel{
+	ProviderInfuraFree: infuraCosts,
+	ProviderInfuraPaid: infuraCosts,
+	ProviderAlchemy: {
+		Methods: map[string]int64{
+			"eth_call":                 26,
+			"eth_blockNumber":          10,
+			"eth_chainId":              0,
+			"eth_getBlockByNumber":     16,
+			"eth_getCode":              26,
+			"eth_getBalance":           19,
+			"eth_getLogs":              75,
+			"alchemy_getTokenBalances": 26,
+			"alchemy_getTokenMetadata": 10,
+		},
+		Default: 26,
+	},
+}
+
+// infuraCosts Infura的credit价目，免费和付费套餐相同
+var infuraCosts = CostModel{
+	Methods: map[string]int64{
+		"eth_call":             80,
+		"eth_blockNumber":      80,
+		"eth_chainId":          5,
+		"eth_getBlockByNumber": 80,
+		"eth_getCode":          80,
+		"eth_getBalance":       80,
+		"eth_getLogs":          255,
+	},
+	Default: 80,
+}
+
+// CallCost 单个JSON-RPC请求的估算费用
+type CallCost struct {
+	Method string
+	Units  int64
+	// Batch 请求是否通过JSON-RPC批量请求发出
+	Batch bool
+}
+
+// WithCostModel 按model估算客户端发出的每个JSON-RPC请求的费用，累计值通过 ComputeUnits 读取，
+// fn 不为nil时每个请求发出后调用一次，可用于按方法上报指标；fn 在发送请求的goroutine中同步执行，应尽快返回
+//
+// 在HTTP传输层统计，重试（包括 WithTransportRetry）、区块头和basefee等所有请求都会计入，
+// 批量请求中的每个请求分别计费；只对 http/https 地址生效。结果是按价目表估算的值，以服务商账单为准
+func WithCostModel(model CostModel, fn func(CallCost)) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.costs = &costMeter{model: model, fn: fn}
+	}
+}
+
+// ComputeUnits 返回自创建客户端（或上次 ResetComputeUnits）以来累计的估算费用，未配置 WithCostModel 时为0
+func (c *MultiTokenQueryClient) ComputeUnits() int64 {
+	if c.costs == nil {
+		return 0
+	}
+	return c.costs.total.Load()
+}
+
+// ResetComputeUnits 清零累计的估算费用并返回清零前的值，用于按周期统计
+func (c *MultiTokenQueryClient) ResetComputeUnits() int64 {
+	if c.costs == nil {
+		return 0
+	}
+	return c.costs.total.Swap(0)
+}
+
+// costMeter 费用的累计值，客户端的所有连接共用
+type costMeter struct {
+	model CostModel
+	fn    func(CallCost)
+	total atomic.Int64
+}
+
+// record 按请求体中的方法计费，请求体可能是单个请求或批量请求的数组
+func (m *costMeter) record(body []byte) {
+	methods, batch, ok := rpcMethods(body)
+	if !ok {
+		return
+	}
+
+	for _, method := range methods {
+		units := m.model.Cost(method)
+		m.total.Add(units)
+		if m.fn != nil {
+			m.fn(CallCost{Method: method, Units: units, Batch: batch})
+		}
+	}
+}
+
+// costTransport 统计经过的每个HTTP请求的费用
+type costTransport struct {
+	base  http.RoundTripper
+	meter *costMeter
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *costTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		t.meter.record(body)
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,141 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Acquisition 一次买入或转入记录
+type Acquisition struct {
+	Token common.Address
+	// Amount 获得的数量，最小单位（与 TokenInfo.Balance 相同，未按decimals换算）
+	Amount *big.Int
+	// PriceUSD 获得时一个完整token单位的美元价格
+	PriceUSD *big.Float
+	Time     time.Time
+}
+
+// CostBasisReport 用户持仓的成本与浮动盈亏
+type CostBasisReport struct {
+	QueryAddress common.Address
+	BlockNumber  *big.Int
+	Timestamp    *big.Int
+	// TotalValueUSD 有价格的持仓的当前美元总价值
+	TotalValueUSD *big.Float
+	// TotalCostUSD、TotalUnrealizedUSD 只汇总既有价格又有成本的token
+	TotalCostUSD       *big.Float
+	TotalUnrealizedUSD *big.Float
+	// Unpriced 预言机没有价格的token，这些token的价值和盈亏为nil
+	Unpriced []common.Address
+	Tokens   []TokenCostBasis
+}
+
+// TokenCostBasis 单个token的成本与浮动盈亏
+type TokenCostBasis struct {
+	Token TokenInfo
+	// ValueUSD 当前美元价值，没有价格时为nil
+	ValueUSD *big.Float
+	// AcquiredAmount 获得记录中的总数量（最小单位），没有获得记录时为nil
+	AcquiredAmount *big.Int
+	// CostUSD 当前持仓按平均成本法计算的成本，没有获得记录时为nil
+	CostUSD *big.Float
+	// UnrealizedUSD 浮动盈亏，即有成本的持仓的当前价值减去 CostUSD；没有价格或没有获得记录时为nil
+	UnrealizedUSD *big.Float
+	// UncoveredAmount 当前余额超出获得记录总量的部分（最小单位），这部分没有成本数据，不计入 CostUSD 和盈亏；
+	// 没有超出时为nil
+	UncoveredAmount *big.Int
+}
+
+// CostBasisReport 查询user在tokens上的当前持仓，结合acquisitions计算每个token的成本和浮动盈亏
+//
+// 成本按平均成本法计算：单位成本为所有获得记录的总成本除以总数量，当前持仓的成本为单位成本乘以持仓量，
+// 已经卖出或转出的部分按同一单位成本扣除。余额超过获得记录总量时，超出部分记为 UncoveredAmount，
+// 只按有成本的部分计算盈亏。没有获得记录的token只报告当前价值；
+// 预言机没有价格的token价值和盈亏为nil，记录在 Unpriced 中；其他价格查询错误直接返回。
+// acquisitions 中不在tokens里的记录被忽略，Amount 为nil或非正、PriceUSD 为nil或为负的记录返回错误
+func (c *MultiTokenQueryClient) CostBasisReport(ctx context.Context, user common.Address, tokens []common.Address, acquisitions []Acquisition, oracle PriceOracle) (*CostBasisReport, error) {
+	type lot struct {
+		amount *big.Int
+		cost   *big.Float
+	}
+	lots := make(map[common.Address]*lot)
+	for i, acquisition := range acquisitions {
+		if acquisition.Amount == nil || acquisition.Amount.Sign() <= 0 {
+			return nil, fmt.Errorf("第%d条获得记录的数量无效: %v", i, acquisition.Amount)
+		}
+		if acquisition.PriceUSD == nil || acquisition.PriceUSD.Sign() < 0 {
+			return nil, fmt.Errorf("第%d条获得记录的价格无效: %v", i, acquisition.PriceUSD)
+		}
+		l := lots[acquisition.Token]
+		if l == nil {
+			l = &lot{amount: new(big.Int), cost: new(big.Float)}
+			lots[acquisition.Token] = l
+		}
+		l.amount.Add(l.amount, acquisition.Amount)
+		// 成本 = 数量（最小单位）× 价格，换算到完整单位在拿到decimals后统一进行
+		l.cost.Add(l.cost, new(big.Float).Mul(new(big.Float).SetInt(acquisition.Amount), acquisition.PriceUSD))
+	}
+
+	result, err := c.QueryMultipleTokens(ctx, user, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	prices, unpriced, err := pricesFor(ctx, oracle, tokenAddresses(result.Tokens))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CostBasisReport{
+		QueryAddress:       result.QueryAddress,
+		BlockNumber:        result.BlockNumber,
+		Timestamp:          result.Timestamp,
+		TotalValueUSD:      new(big.Float),
+		TotalCostUSD:       new(big.Float),
+		TotalUnrealizedUSD: new(big.Float),
+		Tokens:             make([]TokenCostBasis, 0, len(result.Tokens)),
+		Unpriced:           unpriced,
+	}
+	for _, token := range result.Tokens {
+		entry := TokenCostBasis{Token: token}
+		balance := token.Balance
+		if balance == nil {
+			balance = new(big.Int)
+		}
+
+		price, priced := prices[token.TokenAddress]
+		if priced {
+			entry.ValueUSD = new(big.Float).Mul(token.TokenAmount(), price)
+			report.TotalValueUSD.Add(report.TotalValueUSD, entry.ValueUSD)
+		}
+
+		if l := lots[token.TokenAddress]; l != nil {
+			entry.AcquiredAmount = new(big.Int).Set(l.amount)
+			covered := balance
+			if balance.Cmp(l.amount) > 0 {
+				covered = l.amount
+				entry.UncoveredAmount = new(big.Int).Sub(balance, l.amount)
+			}
+
+			// 平均单位成本 × 有成本的持仓量，再换算到完整token单位
+			unit := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(token.Decimals)), nil))
+			entry.CostUSD = new(big.Float).Quo(l.cost, new(big.Float).SetInt(l.amount))
+			entry.CostUSD.Mul(entry.CostUSD, new(big.Float).SetInt(covered))
+			entry.CostUSD.Quo(entry.CostUSD, unit)
+
+			if priced {
+				coveredValue := new(big.Float).Quo(new(big.Float).SetInt(covered), unit)
+				coveredValue.Mul(coveredValue, price)
+				entry.UnrealizedUSD = new(big.Float).Sub(coveredValue, entry.CostUSD)
+				report.TotalCostUSD.Add(report.TotalCostUSD, entry.CostUSD)
+				report.TotalUnrealizedUSD.Add(report.TotalUnrealizedUSD, entry.UnrealizedUSD)
+			}
+		}
+		report.Tokens = append(report.Tokens, entry)
+	}
+	return report, nil
+}
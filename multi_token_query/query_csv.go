@@ -0,0 +1,84 @@
+package contracts
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// resultCSVHeader CSV的列，每个token一行
+var resultCSVHeader = []string{"queryAddress", "contractAddress", "blockNumber", "timestamp", "tokenAddress", "symbol", "decimals", "balance", "formattedBalance"}
+
+// ResultCSVWriter 逐条写出 QueryResult 的CSV写入器，由 StreamResultsCSV 创建，不能并发使用
+//
+// 每写入一条结果就把它的行刷到底层writer，内存占用与结果总数无关；
+// Write 的签名与 ReadResultsJSONGz 等回调一致，可以直接作为回调传入
+type ResultCSVWriter struct {
+	csv *csv.Writer
+}
+
+// StreamResultsCSV 创建写入w的CSV写入器并立即写出表头
+// 地址输出为EIP-55校验和格式，balance 为原始整数，formattedBalance 为按decimals换算后的精确值；
+// 写完后必须调用 Close 把剩余数据刷到w，Close 不会关闭w
+func StreamResultsCSV(w io.Writer) (*ResultCSVWriter, error) {
+	cw := &ResultCSVWriter{csv: csv.NewWriter(w)}
+	if err := cw.csv.Write(resultCSVHeader); err != nil {
+		return nil, fmt.Errorf("写入CSV表头失败: %v", err)
+	}
+	if err := cw.flush(); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write 写出一条结果的所有token行并刷到底层writer
+func (cw *ResultCSVWriter) Write(r *QueryResult) error {
+	var contract, block, timestamp string
+	if r.ContractAddress != (common.Address{}) {
+		contract = r.ContractAddress.Hex()
+	}
+	if r.BlockNumber != nil {
+		block = r.BlockNumber.String()
+	}
+	if r.Timestamp != nil {
+		timestamp = r.Timestamp.String()
+	}
+
+	for _, token := range r.Tokens {
+		balance := "0"
+		if token.Balance != nil {
+			balance = token.Balance.String()
+		}
+		row := []string{
+			r.QueryAddress.Hex(),
+			contract,
+			block,
+			timestamp,
+			token.TokenAddress.Hex(),
+			token.Symbol,
+			strconv.Itoa(int(token.Decimals)),
+			balance,
+			formatBalance(token),
+		}
+		if err := cw.csv.Write(row); err != nil {
+			return fmt.Errorf("写入CSV失败: %v", err)
+		}
+	}
+	return cw.flush()
+}
+
+// Close 把缓冲的数据刷到底层writer
+func (cw *ResultCSVWriter) Close() error {
+	return cw.flush()
+}
+
+func (cw *ResultCSVWriter) flush() error {
+	cw.csv.Flush()
+	if err := cw.csv.Error(); err != nil {
+		return fmt.Errorf("写入CSV失败: %v", err)
+	}
+	return nil
+}
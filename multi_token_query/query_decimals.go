@@ -0,0 +1,161 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DecimalsSource TokenInfo.Decimals 的来源
+type DecimalsSource string
+
+const (
+	// DecimalsSourceChain 单独调用token的 decimals() 得到
+	DecimalsSourceChain DecimalsSource = "chain"
+	// DecimalsSourceRegistry 链上读取失败，取自 WithDecimalsFallback 的注册表
+	DecimalsSourceRegistry DecimalsSource = "registry"
+	// DecimalsSourceUnknown 链上读取失败且注册表中没有该token，Decimals 为0，余额按最小单位显示
+	DecimalsSourceUnknown DecimalsSource = "unknown"
+)
+
+// WithDecimalsFallback 逐个token查询仍然被revert时，如果token的 balanceOf 可用，
+// 只是 symbol() 或 decimals() revert（多见于非标准的老token），单独读取余额，decimals依次取自：
+// 单独调用 decimals()、registry中登记的值，都没有时为0并标记 DecimalsSourceUnknown，
+// 来源记录在 TokenInfo.DecimalsSource 中；balanceOf 也失败的token仍记录在 QueryResult.Failures 中
+//
+// 该选项隐含 WithPerTokenFallback。registry 中的symbol只在链上读不到symbol时使用
+func WithDecimalsFallback(registry *MetadataRegistry) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.perTokenFallback = true
+		c.decimalsFallback = registry
+	}
+}
+
+// queryTokenFallback 绕过查询合约的 querySingleToken 读取单个token：余额先通过 queryBalances，
+// 仍被revert时直接调用token的 balanceOf（兼容不返回数据的token），symbol和decimals直接调用token合约，
+// 未实现（revert）或返回空数据时按读不到处理，节点错误、超时等其他错误作为该token的错误返回。
+// balanceOf 同样只在被revert或返回数据无法解析时返回cause，节点错误以及确认合约代码失败时返回对应的错误。
+// 余额直接读取时无法得到区块时间戳，timestamp为nil；没有配置 WithDecimalsFallback 且读不到decimals时返回cause
+func (c *MultiTokenQueryClient) queryTokenFallback(opts *bind.CallOpts, userAddress, tokenAddress common.Address, cause error) (*TokenInfo, *big.Int, error) {
+	info := &TokenInfo{TokenAddress: tokenAddress}
+	balances, timestamp, _, err := c.queryBalancesOnce(opts, userAddress, []common.Address{tokenAddress})
+	switch {
+	case err == nil:
+		info.Balance = balances[0]
+	case errors.Is(err, ErrRevert):
+		data, err := c.callToken(opts, tokenAddress, balanceOfCalldata(userAddress))
+		if err = classifyCallError(err); errors.Is(err, ErrRevert) {
+			return nil, nil, cause
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("调用token %s 的balanceOf()失败: %w", tokenAddress.Hex(), err)
+		}
+		if info.Balance, info.EmptyBalanceData, err = decodeBalanceOf(data); err != nil {
+			return nil, nil, cause
+		}
+		if info.EmptyBalanceData {
+			if err := c.checkEmptyBalanceOf(opts, tokenAddress); err != nil {
+				return nil, nil, err
+			}
+		}
+	default:
+		return nil, nil, err
+	}
+
+	symbolData, err := c.callTokenMetadata(opts, tokenAddress, selectorSymbol, "symbol")
+	if err != nil {
+		return nil, nil, err
+	}
+	info.Symbol = decodeSymbol(symbolData)
+	var metadata TokenMetadata
+	var registered bool
+	if c.decimalsFallback != nil {
+		metadata, registered = c.decimalsFallback.Get(tokenAddress)
+	}
+	if info.Symbol == "" && registered {
+		info.Symbol = metadata.Symbol
+	}
+
+	decimalsData, err := c.callTokenMetadata(opts, tokenAddress, selectorDecimals, "decimals")
+	if err != nil {
+		return nil, nil, err
+	}
+	if decimals, ok := decodeDecimals(decimalsData); ok {
+		info.Decimals, info.DecimalsSource = decimals, DecimalsSourceChain
+	} else if registered {
+		info.Decimals, info.DecimalsSource = metadata.Decimals, DecimalsSourceRegistry
+	} else if c.decimalsFallback != nil {
+		info.DecimalsSource = DecimalsSourceUnknown
+		c.logger.Warn("无法确定token的decimals，按0处理", "token", tokenAddress.Hex(), "symbol", info.Symbol)
+	} else {
+		return nil, nil, cause
+	}
+	return info, timestamp, nil
+}
+
+// callToken 在opts对应的区块上直接调用token合约
+func (c *MultiTokenQueryClient) callToken(opts *bind.CallOpts, token common.Address, data []byte) ([]byte, error) {
+	ctx, stop := c.closeAware(opts.Context)
+	defer stop()
+	msg := ethereum.CallMsg{To: &token, Data: data}
+	if opts.Pending {
+		return c.conn().client.PendingCallContract(ctx, msg)
+	}
+	return c.conn().client.CallContract(ctx, msg, opts.BlockNumber)
+}
+
+// callTokenMetadata 调用token的 symbol()/decimals()，token没有实现该方法（revert）时返回空数据，
+// 由调用方按空返回值处理（使用 WithDecimalsFallback 的登记数据等）；节点错误、超时等其他错误直接返回
+func (c *MultiTokenQueryClient) callTokenMetadata(opts *bind.CallOpts, token common.Address, selector []byte, name string) ([]byte, error) {
+	data, err := c.callToken(opts, token, selector)
+	if err == nil {
+		return data, nil
+	}
+	if err = classifyCallError(err); errors.Is(err, ErrRevert) {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("调用token %s 的%s()失败: %w", token.Hex(), name, err)
+}
+
+// decodeDecimals 解析 decimals() 的返回值，不是合法的uint8时返回false
+func decodeDecimals(data []byte) (uint8, bool) {
+	if len(data) < 32 {
+		return 0, false
+	}
+	d := new(big.Int).SetBytes(data[:32])
+	if !d.IsUint64() || d.Uint64() > 255 {
+		return 0, false
+	}
+	return uint8(d.Uint64()), true
+}
+
+// decodeSymbol 解析 symbol() 的返回值，兼容string和bytes32（如MKR）两种写法，无法解析时返回空字符串
+func decodeSymbol(data []byte) string {
+	if len(data) == 32 {
+		return string(trimZeros(data))
+	}
+	if len(data) < 64 {
+		return ""
+	}
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return ""
+	}
+	start := offset.Uint64() + 32
+	length := new(big.Int).SetBytes(data[offset.Uint64():start])
+	if !length.IsUint64() || length.Uint64() > uint64(len(data))-start {
+		return ""
+	}
+	return string(data[start : start+length.Uint64()])
+}
+
+// trimZeros 去掉bytes32末尾的0填充
+func trimZeros(b []byte) []byte {
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	return b
+}
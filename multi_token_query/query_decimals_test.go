@@ -0,0 +1,143 @@
+package contracts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// revertQueryContract 让查询合约的所有方法revert，迫使客户端直接读取token合约
+func revertQueryContract(next func(to common.Address, data []byte) ([]byte, error)) func(to common.Address, data []byte) ([]byte, error) {
+	return func(to common.Address, data []byte) ([]byte, error) {
+		if to == fakeContract {
+			return nil, &fakeRPCError{code: RPCCodeExecutionReverted, msg: "execution reverted"}
+		}
+		if next != nil {
+			return next(to, data)
+		}
+		return nil, nil
+	}
+}
+
+func TestTokenFallbackMetadataErrors(t *testing.T) {
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	t.Run("revert", func(t *testing.T) {
+		registry := NewMetadataRegistry()
+		client, node := newFakeClient(t, WithDecimalsFallback(registry))
+		tokens := node.addTokens(1)
+		registry.Set(tokens[0], TokenMetadata{Symbol: "REG", Decimals: 6})
+		node.setHook(revertQueryContract(func(to common.Address, data []byte) ([]byte, error) {
+			if bytes.Equal(data[:4], selectorSymbol) || bytes.Equal(data[:4], selectorDecimals) {
+				return nil, &fakeRPCError{code: RPCCodeExecutionReverted, msg: "execution reverted"}
+			}
+			return nil, nil
+		}))
+
+		result, err := client.QueryMultipleTokens(context.Background(), user, tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Tokens) != 1 {
+			t.Fatalf("结果 = %+v", result)
+		}
+		if token := result.Tokens[0]; token.Symbol != "REG" || token.Decimals != 6 || token.DecimalsSource != DecimalsSourceRegistry {
+			t.Errorf("symbol()/decimals() revert时应使用登记数据，得到 %+v", token)
+		}
+	})
+
+	t.Run("node error", func(t *testing.T) {
+		registry := NewMetadataRegistry()
+		client, node := newFakeClient(t, WithDecimalsFallback(registry))
+		tokens := node.addTokens(1)
+		registry.Set(tokens[0], TokenMetadata{Symbol: "REG", Decimals: 6})
+		node.setHook(revertQueryContract(func(to common.Address, data []byte) ([]byte, error) {
+			if bytes.Equal(data[:4], selectorDecimals) {
+				return nil, &fakeRPCError{code: RPCCodeInternalError, msg: "internal error"}
+			}
+			return nil, nil
+		}))
+
+		result, err := client.QueryMultipleTokens(context.Background(), user, tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Tokens) != 0 || len(result.Failures) != 1 {
+			t.Fatalf("节点错误不应回退到登记数据，得到 %+v", result)
+		}
+		var rpcErr *RPCError
+		if failure := result.Failures[0].Err; !errors.As(failure, &rpcErr) || rpcErr.Code != RPCCodeInternalError || errors.Is(failure, ErrRevert) {
+			t.Errorf("失败原因 = %v，期望节点的 -32603 错误", failure)
+		}
+	})
+}
+
+func TestTokenFallbackEmptyBalanceData(t *testing.T) {
+	client, node := newFakeClient(t, WithPerTokenFallback())
+	tokens := node.addTokens(1)
+	noCode := common.HexToAddress("0x000000000000000000000000000000000000dead")
+	// 非标准token的balanceOf没有返回数据
+	emptyBalanceOf := revertQueryContract(func(to common.Address, data []byte) ([]byte, error) {
+		if to == tokens[0] && bytes.Equal(data[:4], selectorBalanceOf) {
+			return []byte{}, nil
+		}
+		return nil, nil
+	})
+	node.setHook(emptyBalanceOf)
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	result, err := client.QueryMultipleTokens(context.Background(), user, []common.Address{tokens[0], noCode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Tokens) != 1 {
+		t.Fatalf("结果 = %+v", result)
+	}
+	if token := result.Tokens[0]; token.Balance == nil || token.Balance.Sign() != 0 || !token.EmptyBalanceData {
+		t.Errorf("balanceOf没有返回数据时应为余额0并标记 EmptyBalanceData，得到 %+v", token)
+	}
+	// 没有合约代码的地址同样没有返回数据，但不能按余额0处理
+	if len(result.Failures) != 1 || result.Failures[0].Token != noCode || errors.Is(result.Failures[0].Err, ErrRevert) {
+		t.Errorf("没有合约代码的地址应记为失败，得到 %+v", result.Failures)
+	}
+
+	// 节点错误不能被当成revert：直接调用balanceOf失败，或者确认合约代码时 eth_getCode 失败
+	cases := []struct {
+		name string
+		code int
+		set  func()
+	}{
+		{"balanceOf", RPCCodeLimitExceeded, func() {
+			node.setHook(revertQueryContract(func(to common.Address, data []byte) ([]byte, error) {
+				if to == tokens[0] && bytes.Equal(data[:4], selectorBalanceOf) {
+					return nil, &fakeRPCError{code: RPCCodeLimitExceeded, msg: "rate limited"}
+				}
+				return nil, nil
+			}))
+		}},
+		{"getCode", RPCCodeInternalError, func() {
+			node.setHook(emptyBalanceOf)
+			node.setCodeError(&fakeRPCError{code: RPCCodeInternalError, msg: "internal error"})
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.set()
+			result, err := client.QueryMultipleTokens(context.Background(), user, tokens)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Tokens) != 0 || len(result.Failures) != 1 {
+				t.Fatalf("结果 = %+v", result)
+			}
+			failure := result.Failures[0].Err
+			var rpcErr *RPCError
+			if !errors.As(failure, &rpcErr) || rpcErr.Code != tc.code || errors.Is(failure, ErrRevert) {
+				t.Errorf("失败原因 = %v，期望节点的 %d 错误而不是revert", failure, tc.code)
+			}
+		})
+	}
+}
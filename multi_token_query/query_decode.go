@@ -0,0 +1,156 @@
+package contracts
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// decodeQueryResult 把 queryMultipleTokens 解码出的匿名struct映射为 QueryResult
+//
+// go-ethereum 对嵌套tuple会生成匿名struct，字段名由ABI的组件名首字母大写得到，
+// 这里按字段名通过反射读取，不依赖匿名struct的具体类型。
+func decodeQueryResult(v interface{}) (*QueryResult, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("查询结果类型错误: %T", v)
+	}
+
+	result := &QueryResult{}
+	var err error
+
+	if result.QueryAddress, err = addressField(rv, "QueryAddress"); err != nil {
+		return nil, err
+	}
+	if result.Timestamp, err = bigIntField(rv, "Timestamp"); err != nil {
+		return nil, err
+	}
+	if result.BlockNumber, err = bigIntField(rv, "BlockNumber"); err != nil {
+		return nil, err
+	}
+
+	tokens, err := field(rv, "Tokens")
+	if err != nil {
+		return nil, err
+	}
+	if tokens.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("字段Tokens类型错误: %s", tokens.Type())
+	}
+
+	result.Tokens = make([]TokenInfo, tokens.Len())
+	for i := 0; i < tokens.Len(); i++ {
+		info, err := decodeTokenInfo(tokens.Index(i))
+		if err != nil {
+			return nil, fmt.Errorf("解析第%d个token失败: %v", i, err)
+		}
+		result.Tokens[i] = info
+	}
+
+	return result, nil
+}
+
+// decodeTokenInfo 把单个 TokenInfo 匿名struct映射为 TokenInfo
+func decodeTokenInfo(v reflect.Value) (TokenInfo, error) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return TokenInfo{}, fmt.Errorf("token信息类型错误: %s", v.Type())
+	}
+
+	var info TokenInfo
+	var err error
+
+	if info.TokenAddress, err = addressField(v, "TokenAddress"); err != nil {
+		return TokenInfo{}, err
+	}
+	symbol, err := field(v, "Symbol")
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	if symbol.Kind() != reflect.String {
+		return TokenInfo{}, fmt.Errorf("字段Symbol类型错误: %s", symbol.Type())
+	}
+	info.Symbol = symbol.String()
+
+	if info.Decimals, info.DecimalsClamped, err = decimalsField(v, "Decimals"); err != nil {
+		return TokenInfo{}, err
+	}
+	if info.Balance, err = bigIntField(v, "Balance"); err != nil {
+		return TokenInfo{}, err
+	}
+
+	return info, nil
+}
+
+// field 按名称读取struct字段
+func field(v reflect.Value, name string) (reflect.Value, error) {
+	f := v.FieldByName(name)
+	if !f.IsValid() {
+		return reflect.Value{}, fmt.Errorf("查询结果缺少字段%s", name)
+	}
+	return f, nil
+}
+
+func addressField(v reflect.Value, name string) (common.Address, error) {
+	f, err := field(v, name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	addr, ok := f.Interface().(common.Address)
+	if !ok {
+		return common.Address{}, fmt.Errorf("字段%s类型错误: %s", name, f.Type())
+	}
+	return addr, nil
+}
+
+func bigIntField(v reflect.Value, name string) (*big.Int, error) {
+	f, err := field(v, name)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := f.Interface().(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("字段%s类型错误: %s", name, f.Type())
+	}
+	return n, nil
+}
+
+// decimalsField 读取decimals字段
+// ABI声明为uint8时解码为uint8，声明为更宽的uint（如uint256）时解码为 *big.Int 或其他无符号整数。
+// 超过255的值截断为255，并通过clamped返回true，避免个别异常token导致整个结果解码失败
+func decimalsField(v reflect.Value, name string) (decimals uint8, clamped bool, err error) {
+	f, err := field(v, name)
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch d := f.Interface().(type) {
+	case uint8:
+		return d, false, nil
+	case *big.Int:
+		if d == nil || d.Sign() < 0 {
+			return 0, false, fmt.Errorf("字段%s的值无效: %v", name, d)
+		}
+		if !d.IsUint64() {
+			return math.MaxUint8, true, nil
+		}
+		return clampDecimals(d.Uint64())
+	}
+
+	switch f.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return clampDecimals(f.Uint())
+	}
+
+	return 0, false, fmt.Errorf("字段%s类型错误: %s", name, f.Type())
+}
+
+// clampDecimals 把decimals截断到uint8范围
+func clampDecimals(d uint64) (uint8, bool, error) {
+	if d > math.MaxUint8 {
+		return math.MaxUint8, true, nil
+	}
+	return uint8(d), false, nil
+}
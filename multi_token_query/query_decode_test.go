@@ -0,0 +1,170 @@
+package contracts
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 模拟 go-ethereum 对嵌套tuple生成的匿名struct
+type decodedToken struct {
+	TokenAddress common.Address
+	Symbol       string
+	Decimals     uint8
+	Balance      *big.Int
+}
+
+type decodedResult struct {
+	QueryAddress common.Address
+	Tokens       []decodedToken
+	Timestamp    *big.Int
+	BlockNumber  *big.Int
+}
+
+func TestDecodeQueryResult(t *testing.T) {
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	usdc := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	dai := common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+
+	tests := []struct {
+		name    string
+		input   interface{}
+		want    *QueryResult
+		wantErr bool
+	}{
+		{
+			name: "两个token",
+			input: decodedResult{
+				QueryAddress: user,
+				Tokens: []decodedToken{
+					{TokenAddress: usdc, Symbol: "USDC", Decimals: 6, Balance: big.NewInt(1500000)},
+					{TokenAddress: dai, Symbol: "DAI", Decimals: 18, Balance: big.NewInt(7)},
+				},
+				Timestamp:   big.NewInt(1700000000),
+				BlockNumber: big.NewInt(18000000),
+			},
+			want: &QueryResult{
+				QueryAddress: user,
+				Tokens: []TokenInfo{
+					{TokenAddress: usdc, Symbol: "USDC", Decimals: 6, Balance: big.NewInt(1500000)},
+					{TokenAddress: dai, Symbol: "DAI", Decimals: 18, Balance: big.NewInt(7)},
+				},
+				Timestamp:   big.NewInt(1700000000),
+				BlockNumber: big.NewInt(18000000),
+			},
+		},
+		{
+			name: "指针",
+			input: &decodedResult{
+				QueryAddress: user,
+				Timestamp:    big.NewInt(1),
+				BlockNumber:  big.NewInt(2),
+			},
+			want: &QueryResult{QueryAddress: user, Tokens: []TokenInfo{}, Timestamp: big.NewInt(1), BlockNumber: big.NewInt(2)},
+		},
+		{
+			name:    "缺少字段",
+			input:   struct{ QueryAddress common.Address }{user},
+			wantErr: true,
+		},
+		{
+			name:    "不是struct",
+			input:   []int{1},
+			wantErr: true,
+		},
+		{
+			name: "字段类型错误",
+			input: struct {
+				QueryAddress common.Address
+				Tokens       []decodedToken
+				Timestamp    uint64
+				BlockNumber  *big.Int
+			}{QueryAddress: user},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeQueryResult(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，实际为 %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertResultEqual(t, got, tt.want)
+		})
+	}
+}
+
+func assertResultEqual(t *testing.T, got, want *QueryResult) {
+	t.Helper()
+	if got.QueryAddress != want.QueryAddress || got.Timestamp.Cmp(want.Timestamp) != 0 || got.BlockNumber.Cmp(want.BlockNumber) != 0 {
+		t.Fatalf("结果头部不一致: got %+v, want %+v", got, want)
+	}
+	if len(got.Tokens) != len(want.Tokens) {
+		t.Fatalf("token数 = %d，期望 %d", len(got.Tokens), len(want.Tokens))
+	}
+	for i := range want.Tokens {
+		g, w := got.Tokens[i], want.Tokens[i]
+		if g.TokenAddress != w.TokenAddress || g.Symbol != w.Symbol || g.Decimals != w.Decimals || g.Balance.Cmp(w.Balance) != 0 {
+			t.Errorf("第%d个token = %+v，期望 %+v", i, g, w)
+		}
+	}
+}
+
+func TestDecodeTokenInfoWideDecimals(t *testing.T) {
+	type wideToken struct {
+		TokenAddress common.Address
+		Symbol       string
+		Decimals     *big.Int
+		Balance      *big.Int
+	}
+	huge, _ := new(big.Int).SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935", 10)
+
+	tests := []struct {
+		name        string
+		decimals    *big.Int
+		want        uint8
+		wantClamped bool
+		wantErr     bool
+	}{
+		{name: "uint256的18", decimals: big.NewInt(18), want: 18},
+		{name: "255", decimals: big.NewInt(255), want: 255},
+		{name: "256", decimals: big.NewInt(256), want: 255, wantClamped: true},
+		{name: "2^256-1", decimals: huge, want: 255, wantClamped: true},
+		{name: "nil", decimals: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := decodeTokenInfo(reflect.ValueOf(wideToken{Symbol: "ODD", Decimals: tt.decimals, Balance: big.NewInt(1)}))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，实际为 %+v", info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Decimals != tt.want || info.DecimalsClamped != tt.wantClamped {
+				t.Fatalf("decimals = %d, clamped = %v，期望 %d, %v", info.Decimals, info.DecimalsClamped, tt.want, tt.wantClamped)
+			}
+		})
+	}
+}
+
+func TestFlagSuspiciousDecimalsClamped(t *testing.T) {
+	c := &MultiTokenQueryClient{maxDecimals: defaultMaxDecimals, logger: discardLogger()}
+	result := &QueryResult{Tokens: []TokenInfo{{Decimals: 18}, {Decimals: 255, DecimalsClamped: true}}}
+	c.flagSuspiciousDecimals(result)
+	if result.Tokens[0].SuspiciousDecimals || !result.Tokens[1].SuspiciousDecimals {
+		t.Fatalf("SuspiciousDecimals = %v, %v，期望 false, true", result.Tokens[0].SuspiciousDecimals, result.Tokens[1].SuspiciousDecimals)
+	}
+}
@@ -0,0 +1,122 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// topicTransfer ERC20/ERC721 Transfer(address,address,uint256) 事件的topic
+var topicTransfer = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// defaultLogRange DiscoverTokens 每次 eth_getLogs 查询的默认区块数，多数服务商允许的范围在2000到10000之间
+const defaultLogRange = 2000
+
+// WithLogRange 设置 DiscoverTokens 每次 eth_getLogs 查询的区块数，默认2000
+func WithLogRange(blocks uint64) Option {
+	return func(c *MultiTokenQueryClient) {
+		if blocks > 0 {
+			c.logRange = blocks
+		}
+	}
+}
+
+// logRangeMessages 服务商因区块范围过大或结果过多拒绝 eth_getLogs 时常见的错误信息片段（小写）
+var logRangeMessages = []string{
+	"block range",
+	"range is too large",
+	"query returned more than",
+	"too many results",
+	"exceed maximum block range",
+	"logs limit",
+}
+
+// isLogRangeError 判断 eth_getLogs 的错误是否因为查询范围过大
+func isLogRangeError(err error) bool {
+	if isSizeLimitError(err) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range logRangeMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverTokens 扫描[fromBlock, toBlock]内转入user的ERC20 Transfer事件，返回去重后的token地址，
+// 可直接作为 QueryMultipleTokens 的token列表。fromBlock为nil时从创世区块开始，toBlock为nil时到最新区块
+//
+// 按 WithLogRange 把区块范围拆成多次 eth_getLogs，服务商以范围过大拒绝时自动把范围减半重试。
+// 扫描较长的区块范围需要大量请求，在公共节点上很容易触发限流，建议只扫描必要的范围并使用付费节点。
+// ERC721 的Transfer事件签名相同但有4个topic，会被排除；返回的地址按首次出现的顺序排列，其中可能包含诈骗token
+func (c *MultiTokenQueryClient) DiscoverTokens(ctx context.Context, user common.Address, fromBlock, toBlock *big.Int) (tokens []common.Address, err error) {
+	ctx, end := c.startSpan(ctx, "DiscoverTokens", user, SpanAttributes{})
+	defer func() { end(toBlock, err) }()
+
+	ctx, stop := c.closeAware(ctx)
+	defer stop()
+	client := c.conn().client
+	var from, to uint64
+	if fromBlock != nil {
+		from = fromBlock.Uint64()
+	}
+	if toBlock != nil {
+		to = toBlock.Uint64()
+	} else {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("查询当前区块号失败: %w", err)
+		}
+		to = head
+	}
+	if from > to {
+		return nil, fmt.Errorf("起始区块%d大于结束区块%d", from, to)
+	}
+
+	userTopic := common.BytesToHash(user.Bytes())
+	seen := make(map[common.Address]bool)
+	rangeSize := c.logRange
+
+	for start := from; start <= to; {
+		chunkEnd := start + rangeSize - 1
+		if chunkEnd > to || chunkEnd < start {
+			chunkEnd = to
+		}
+
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(chunkEnd),
+			Topics:    [][]common.Hash{{topicTransfer}, nil, {userTopic}},
+		})
+		if err != nil {
+			if isLogRangeError(err) && rangeSize > 1 {
+				rangeSize /= 2
+				c.logger.Debug("eth_getLogs范围过大，缩小后重试", "blocks", rangeSize)
+				continue
+			}
+			return nil, fmt.Errorf("查询区块%d到%d的Transfer事件失败: %w", start, chunkEnd, err)
+		}
+
+		for _, log := range logs {
+			if len(log.Topics) != 3 || seen[log.Address] {
+				continue
+			}
+			seen[log.Address] = true
+			tokens = append(tokens, log.Address)
+		}
+
+		if chunkEnd == to {
+			break
+		}
+		start = chunkEnd + 1
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,252 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultENSRegistry 以太坊主网（以及Sepolia、Holesky）的ENS注册表地址
+var DefaultENSRegistry = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+// ensCacheTTL ENS解析结果的缓存时间，ENS记录很少变化
+const ensCacheTTL = time.Hour
+
+// ErrENSNotFound ENS名称没有设置解析器或地址记录
+var ErrENSNotFound = errors.New("ENS名称未解析到地址")
+
+var (
+	selectorENSResolver = crypto.Keccak256([]byte("resolver(bytes32)"))[:4]
+	selectorENSAddr     = crypto.Keccak256([]byte("addr(bytes32)"))[:4]
+	selectorENSName     = crypto.Keccak256([]byte("name(bytes32)"))[:4]
+)
+
+// WithENSNames 查询后通过ENS反向解析为结果填充 QueryResult.ENSName
+//
+// 反向记录可以由地址所有者随意设置，因此只有名称再正向解析回同一地址时才采用，否则视为没有名称。
+// 没有反向记录或解析失败时ENSName为空，不影响余额结果。解析结果缓存一小时。
+// 默认使用主网的ENS注册表，其他部署了ENS的链用 WithENSRegistry 指定
+func WithENSNames() Option {
+	return func(c *MultiTokenQueryClient) {
+		c.ensNames = true
+	}
+}
+
+// WithENSRegistry 指定ENS注册表地址，影响 WithENSNames、ResolveENS、LookupENSName 和 SameAddress
+func WithENSRegistry(registry common.Address) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.ensRegistry = registry
+	}
+}
+
+// ensCache 缓存ENS正向和反向解析结果，并发安全
+type ensCache struct {
+	mu      sync.Mutex
+	forward map[string]ensEntry
+	reverse map[common.Address]ensEntry
+}
+
+type ensEntry struct {
+	addr    common.Address
+	name    string
+	expires time.Time
+}
+
+// ResolveENS 把ENS名称正向解析为地址，结果缓存一小时
+// 名称按小写处理，未做完整的ENSIP-15规范化，包含非ASCII字符的名称需要调用方先规范化；
+// 名称没有解析器或地址记录时返回的错误满足 errors.Is(err, ErrENSNotFound)
+func (c *MultiTokenQueryClient) ResolveENS(ctx context.Context, name string) (common.Address, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if addr, ok := c.ens.lookupForward(name); ok {
+		return addr, nil
+	}
+
+	node := ensNamehash(name)
+	resolver, err := c.ensResolver(ctx, node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("解析ENS名称%s失败: %w", name, err)
+	}
+	data, err := c.ensCall(ctx, resolver, selectorENSAddr, node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("解析ENS名称%s失败: %w", name, err)
+	}
+	if len(data) < 32 || common.BytesToAddress(data[:32]) == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%w: %s", ErrENSNotFound, name)
+	}
+
+	addr := common.BytesToAddress(data[:32])
+	c.ens.storeForward(name, addr)
+	return addr, nil
+}
+
+// LookupENSName 反向解析地址的ENS名称，并正向解析校验名称确实指向该地址，结果缓存一小时
+// 没有反向记录或校验不一致时返回空字符串和nil
+func (c *MultiTokenQueryClient) LookupENSName(ctx context.Context, addr common.Address) (string, error) {
+	if name, ok := c.ens.lookupReverse(addr); ok {
+		return name, nil
+	}
+
+	name, err := c.reverseENSName(ctx, addr)
+	if err != nil {
+		return "", err
+	}
+	if name != "" {
+		forward, err := c.ResolveENS(ctx, name)
+		if errors.Is(err, ErrENSNotFound) || (err == nil && forward != addr) {
+			name = ""
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	c.ens.storeReverse(addr, name)
+	return name, nil
+}
+
+// SameAddress 判断两个地址是否相同，每个参数可以是十六进制地址（大小写不敏感）或ENS名称
+func (c *MultiTokenQueryClient) SameAddress(ctx context.Context, a, b string) (bool, error) {
+	addrA, err := c.resolveAddressOrName(ctx, a)
+	if err != nil {
+		return false, err
+	}
+	addrB, err := c.resolveAddressOrName(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	return addrA == addrB, nil
+}
+
+func (c *MultiTokenQueryClient) resolveAddressOrName(ctx context.Context, s string) (common.Address, error) {
+	if common.IsHexAddress(strings.TrimSpace(s)) {
+		return common.HexToAddress(strings.TrimSpace(s)), nil
+	}
+	return c.ResolveENS(ctx, s)
+}
+
+// reverseENSName 读取 <addr>.addr.reverse 的name记录，没有记录时返回空字符串
+func (c *MultiTokenQueryClient) reverseENSName(ctx context.Context, addr common.Address) (string, error) {
+	node := ensNamehash(strings.ToLower(addr.Hex()[2:]) + ".addr.reverse")
+	resolver, err := c.ensResolver(ctx, node)
+	if errors.Is(err, ErrENSNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("反向解析%s失败: %w", addr.Hex(), err)
+	}
+
+	data, err := c.ensCall(ctx, resolver, selectorENSName, node)
+	if errors.Is(err, ErrRevert) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("反向解析%s失败: %w", addr.Hex(), err)
+	}
+	if len(data) == 0 {
+		return "", nil
+	}
+	stringType, _ := abi.NewType("string", "", nil)
+	values, err := abi.Arguments{{Type: stringType}}.Unpack(data)
+	if err != nil || len(values) == 0 {
+		return "", fmt.Errorf("反向解析%s失败: 无法解码name: %v", addr.Hex(), err)
+	}
+	name, _ := values[0].(string)
+	return name, nil
+}
+
+// ensResolver 从注册表读取节点的解析器，没有解析器时返回 ErrENSNotFound
+func (c *MultiTokenQueryClient) ensResolver(ctx context.Context, node common.Hash) (common.Address, error) {
+	registry := c.ensRegistry
+	if registry == (common.Address{}) {
+		registry = DefaultENSRegistry
+	}
+	data, err := c.ensCall(ctx, registry, selectorENSResolver, node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(data) < 32 || common.BytesToAddress(data[:32]) == (common.Address{}) {
+		return common.Address{}, ErrENSNotFound
+	}
+	return common.BytesToAddress(data[:32]), nil
+}
+
+// ensCall 以 selector+node 为calldata调用to的只读方法
+func (c *MultiTokenQueryClient) ensCall(ctx context.Context, to common.Address, selector []byte, node common.Hash) ([]byte, error) {
+	ctx, stop := c.closeAware(ctx)
+	defer stop()
+	msg := ethereum.CallMsg{To: &to, Data: append(append([]byte{}, selector...), node[:]...)}
+	data, err := c.conn().client.CallContract(ctx, msg, nil)
+	return data, classifyCallError(err)
+}
+
+// fillENSName 为结果填充查询地址的ENS名称，失败只记录警告
+func (c *MultiTokenQueryClient) fillENSName(ctx context.Context, result *QueryResult) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	name, err := c.LookupENSName(ctx, result.QueryAddress)
+	if err != nil {
+		c.logger.Warn("反向解析ENS名称失败", "user", c.walletLabel(result.QueryAddress), "err", err)
+		return
+	}
+	result.ENSName = name
+}
+
+// ensNamehash 按EIP-137计算名称的namehash
+func ensNamehash(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := crypto.Keccak256([]byte(labels[i]))
+		node = common.BytesToHash(crypto.Keccak256(node[:], label))
+	}
+	return node
+}
+
+func (e *ensCache) lookupForward(name string) (common.Address, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	entry, ok := e.forward[name]
+	if !ok || !time.Now().Before(entry.expires) {
+		return common.Address{}, false
+	}
+	return entry.addr, true
+}
+
+func (e *ensCache) lookupReverse(addr common.Address) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	entry, ok := e.reverse[addr]
+	if !ok || !time.Now().Before(entry.expires) {
+		return "", false
+	}
+	return entry.name, true
+}
+
+func (e *ensCache) storeForward(name string, addr common.Address) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.forward == nil {
+		e.forward = make(map[string]ensEntry)
+	}
+	e.forward[name] = ensEntry{addr: addr, expires: time.Now().Add(ensCacheTTL)}
+}
+
+func (e *ensCache) storeReverse(addr common.Address, name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.reverse == nil {
+		e.reverse = make(map[common.Address]ensEntry)
+	}
+	e.reverse[addr] = ensEntry{name: name, expires: time.Now().Add(ensCacheTTL)}
+}
@@ -0,0 +1,89 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ERC20 方法选择器
+var (
+	selectorSymbol   = crypto.Keccak256([]byte("symbol()"))[:4]
+	selectorDecimals = crypto.Keccak256([]byte("decimals()"))[:4]
+)
+
+// FilterERC20 从候选地址中筛选出看起来是ERC20的合约
+// 判定条件：地址上有合约代码，且 decimals() 返回一个uint、symbol() 调用成功。
+// 探测在与查询相同的区块上进行（受 WithConfirmationDepth 影响）；revert视为不是ERC20，
+// 网络、限流等其他错误不会被当作"不是ERC20"静默丢弃，而是带上对应地址合并返回。
+// 各候选地址并发探测（受 WithConcurrency 限制），返回结果保持candidates中的相对顺序
+func (c *MultiTokenQueryClient) FilterERC20(ctx context.Context, candidates []common.Address) ([]common.Address, error) {
+	ctx, stop := c.closeAware(ctx)
+	defer stop()
+	opts, err := c.resolvePinnedOpts(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+
+	valid := make([]bool, len(candidates))
+	errs := make([]error, len(candidates))
+	runBounded(c.concurrency, len(candidates), func(i int) {
+		ok, err := c.looksLikeERC20(opts, candidates[i])
+		if err != nil {
+			errs[i] = fmt.Errorf("探测%s失败: %w", candidates[i].Hex(), err)
+			return
+		}
+		valid[i] = ok
+	})
+
+	// 超时、取消或客户端关闭时探测结果不可信，直接返回错误
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	tokens := make([]common.Address, 0, len(candidates))
+	for i, ok := range valid {
+		if ok {
+			tokens = append(tokens, candidates[i])
+		}
+	}
+	return tokens, nil
+}
+
+// looksLikeERC20 探测单个地址是否实现了ERC20的元数据方法
+// 调用revert时返回false，其他调用错误原样返回
+func (c *MultiTokenQueryClient) looksLikeERC20(opts *bind.CallOpts, token common.Address) (bool, error) {
+	client := c.conn().client
+	code, err := client.CodeAt(opts.Context, token, opts.BlockNumber)
+	if err != nil {
+		return false, err
+	}
+	if len(code) == 0 {
+		return false, nil
+	}
+
+	decimals, err := c.probeERC20Call(opts, token, selectorDecimals)
+	if err != nil || len(decimals) < 32 {
+		return false, err
+	}
+
+	// 部分老token（如MKR）的symbol返回bytes32而不是string，这里只要求调用成功且有返回数据
+	symbol, err := c.probeERC20Call(opts, token, selectorSymbol)
+	return err == nil && len(symbol) > 0, err
+}
+
+// probeERC20Call 调用token的元数据方法，revert时返回空数据和nil错误
+func (c *MultiTokenQueryClient) probeERC20Call(opts *bind.CallOpts, token common.Address, data []byte) ([]byte, error) {
+	out, err := c.callToken(opts, token, data)
+	if err = classifyCallError(err); errors.Is(err, ErrRevert) {
+		return nil, nil
+	}
+	return out, err
+}
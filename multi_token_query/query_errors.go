@@ -0,0 +1,173 @@
+package contracts
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrRevert 合约调用被revert，可以用 errors.Is 判断，用 errors.As 取出 *RevertError 查看原因
+var ErrRevert = errors.New("execution reverted")
+
+// ErrHistoricalUnsupported 节点没有所查询历史区块的状态（非归档节点或状态已被裁剪），需要改用归档节点
+var ErrHistoricalUnsupported = errors.New("节点不支持历史状态查询，请使用归档节点")
+
+// ErrClientClosed 客户端已经 Close，调用被取消或拒绝
+var ErrClientClosed = errors.New("客户端已关闭")
+
+// RevertError 合约调用revert的详细信息
+type RevertError struct {
+	// Reason revert原因，节点只返回裸的 "execution reverted" 时为空字符串
+	Reason string
+	// Data 原始revert数据，可能为空
+	Data []byte
+
+	err error
+}
+
+// Error 实现 error
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return ErrRevert.Error()
+	}
+	return ErrRevert.Error() + ": " + e.Reason
+}
+
+// Is 使 errors.Is(err, ErrRevert) 成立
+func (e *RevertError) Is(target error) bool {
+	return target == ErrRevert
+}
+
+// Unwrap 返回节点返回的原始错误
+func (e *RevertError) Unwrap() error {
+	return e.err
+}
+
+// classifyCallError 识别revert错误并转换为 *RevertError，节点返回的其他JSON-RPC错误转换为 *RPCError，其余错误原样返回
+//
+// 节点返回revert的方式有两种：带原因的 "execution reverted: xxx"（通常附带 Error(string) 编码的data），
+// 以及不带任何数据的裸 "execution reverted"。两种都归类为revert，原因分别为解码出的字符串和空字符串，
+// 避免被当成连接错误进行无意义的重试
+func classifyCallError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var revertErr *RevertError
+	if errors.As(err, &revertErr) {
+		return err
+	}
+	err = wrapRPCError(err)
+	if !isRevertError(err) {
+		return err
+	}
+
+	data := revertData(err)
+	reason := ""
+	if decoded, unpackErr := abi.UnpackRevert(data); unpackErr == nil {
+		reason = decoded
+	} else if _, after, found := strings.Cut(err.Error(), "execution reverted: "); found {
+		reason = after
+	}
+
+	return &RevertError{Reason: reason, Data: data, err: err}
+}
+
+// revertSelectors Solidity内置的 Error(string) 和 Panic(uint256) 的选择器，revert数据以它们开头
+var revertSelectors = [][]byte{
+	{0x08, 0xc3, 0x79, 0xa0},
+	{0x4e, 0x48, 0x7b, 0x71},
+}
+
+// isRevertError 判断错误是否表示revert：JSON-RPC错误码为3（geth/erigon带data的revert）、
+// 错误信息为 "execution reverted"（geth/erigon，可能带原因）或以 "Reverted" 开头（nethermind），
+// 或者错误data是 Error(string)/Panic(uint256) 编码的revert数据。
+// 只是信息中含有 "reverted" 的其他错误（如服务商的限流提示）不算revert
+func isRevertError(err error) bool {
+	msg := err.Error()
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		if rpcErr.Code == RPCCodeExecutionReverted {
+			return true
+		}
+		msg = rpcErr.Message
+	}
+
+	lower := strings.ToLower(msg)
+	if strings.Contains(lower, "execution reverted") || strings.HasPrefix(lower, "reverted") {
+		return true
+	}
+
+	data := revertData(err)
+	for _, selector := range revertSelectors {
+		if bytes.HasPrefix(data, selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// revertData 取出JSON-RPC错误中十六进制编码的data，没有或无法解码时返回nil
+func revertData(err error) []byte {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil
+	}
+	s, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil
+	}
+	data, _ := hexutil.Decode(s)
+	return data
+}
+
+// sizeLimitMessages 服务商返回的响应或请求超出大小限制时常见的错误信息片段（小写）
+var sizeLimitMessages = []string{
+	"response size",
+	"response too large",
+	"too large",
+	"size limit",
+	"size exceeded",
+	"exceeds the limit",
+}
+
+// isSizeLimitError 判断错误是否由响应或请求超出服务商的大小限制引起，包括HTTP 413
+func isSizeLimitError(err error) bool {
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusRequestEntityTooLarge {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range sizeLimitMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// historicalStateMessages 节点缺少历史状态时常见的错误信息片段（小写）
+// 依次对应 geth（missing trie node）、erigon/reth以及各服务商的写法
+var historicalStateMessages = []string{
+	"missing trie node",
+	"state not available",
+	"state is not available",
+	"historical state",
+	"state unavailable",
+	"state has been pruned",
+}
+
+// isHistoricalStateError 判断错误是否因为节点没有对应区块的状态
+func isHistoricalStateError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range historicalStateMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
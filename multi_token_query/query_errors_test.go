@@ -0,0 +1,94 @@
+package contracts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// 0x08c379a0 + abi.encode("insufficient balance")
+const revertInsufficientBalance = "0x08c379a0" +
+	"0000000000000000000000000000000000000000000000000000000000000020" +
+	"0000000000000000000000000000000000000000000000000000000000000014" +
+	"696e73756666696369656e742062616c616e6365000000000000000000000000"
+
+func TestClassifyCallErrorRevertShapes(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantRevert bool
+		wantReason string
+	}{
+		{
+			name:       "带原因和data",
+			err:        &fakeRPCError{code: RPCCodeExecutionReverted, msg: "execution reverted: insufficient balance", data: revertInsufficientBalance},
+			wantRevert: true,
+			wantReason: "insufficient balance",
+		},
+		{
+			name:       "裸revert",
+			err:        &fakeRPCError{code: -32000, msg: "execution reverted"},
+			wantRevert: true,
+		},
+		{
+			name:       "非RPC错误的裸revert",
+			err:        errors.New("execution reverted"),
+			wantRevert: true,
+		},
+		{
+			name:       "错误码3不带原因",
+			err:        &fakeRPCError{code: RPCCodeExecutionReverted, msg: "VM execution error.", data: "0x"},
+			wantRevert: true,
+		},
+		{
+			name:       "nethermind",
+			err:        &fakeRPCError{code: -32015, msg: "Reverted"},
+			wantRevert: true,
+		},
+		{
+			name:       "只有revert数据",
+			err:        &fakeRPCError{code: -32000, msg: "VM execution error.", data: revertInsufficientBalance},
+			wantRevert: true,
+			wantReason: "insufficient balance",
+		},
+		{
+			name: "信息中含有reverted的限流错误",
+			err:  &fakeRPCError{code: -32005, msg: "request reverted by rate limiter"},
+		},
+		{
+			name: "连接错误",
+			err:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyCallError(tt.err)
+			if got := errors.Is(err, ErrRevert); got != tt.wantRevert {
+				t.Fatalf("errors.Is(%v, ErrRevert) = %v，期望 %v", err, got, tt.wantRevert)
+			}
+			if !tt.wantRevert {
+				return
+			}
+			var revertErr *RevertError
+			if !errors.As(err, &revertErr) {
+				t.Fatalf("%v 不是 *RevertError", err)
+			}
+			if revertErr.Reason != tt.wantReason {
+				t.Errorf("Reason = %q，期望 %q", revertErr.Reason, tt.wantReason)
+			}
+			if isRetryableError(err) {
+				t.Errorf("revert 不应重试: %v", err)
+			}
+		})
+	}
+}
+
+func TestRevertErrorData(t *testing.T) {
+	err := classifyCallError(&fakeRPCError{code: RPCCodeExecutionReverted, msg: "execution reverted", data: revertInsufficientBalance})
+	var revertErr *RevertError
+	if !errors.As(err, &revertErr) || hexutil.Encode(revertErr.Data) != revertInsufficientBalance {
+		t.Fatalf("Data = %x", revertErr.Data)
+	}
+}
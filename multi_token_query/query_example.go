@@ -4,90 +4,467 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"math/big"
+	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // TokenInfo 表示单个token的信息
 type TokenInfo struct {
-	TokenAddress common.Address
-	Symbol       string
-	Decimals     uint8
-	Balance      *big.Int
+	TokenAddress common.Address `json:"tokenAddress"`
+	Symbol       string         `json:"symbol"`
+	Decimals     uint8          `json:"decimals"`
+	Balance      *big.Int       `json:"balance"`
+	// DecimalsClamped token返回的decimals超过255，Decimals已被截断为255，格式化后的数值不可信
+	DecimalsClamped bool `json:"decimalsClamped,omitempty"`
+	// SuspiciousDecimals decimals超过 WithMaxDecimals 设置的阈值（默认36），多见于恶意或有问题的token，
+	// 据此显示的金额没有意义，界面上应避免直接展示
+	SuspiciousDecimals bool `json:"suspiciousDecimals,omitempty"`
+	// Shares 转换前balanceOf返回的原始值，仅对配置了 WithShareConverters 转换函数的token填充，
+	// 此时Balance为转换后的底层资产数量
+	Shares *big.Int `json:"shares,omitempty"`
+	// TotalSupply token总供应量，仅在启用 WithTotalSupply 时填充，查询失败时为nil
+	TotalSupply *big.Int `json:"totalSupply,omitempty"`
+	// EmptyBalanceData 该token的 balanceOf 没有返回任何数据（非标准实现），Balance按0处理
+	EmptyBalanceData bool `json:"emptyBalanceData,omitempty"`
+	// DecimalsSource Decimals 的来源，仅在 WithDecimalsFallback 绕过查询合约读取该token时填充，
+	// 为空表示由查询合约（或 WithMetadataRegistry 的注册表）正常给出
+	DecimalsSource DecimalsSource `json:"decimalsSource,omitempty"`
+	// TransferBlocked 模拟用户转出全部余额时被revert或返回false，多见于蜜罐token，
+	// 仅在启用 WithTransferProbe 时检测，模拟失败（如节点不支持状态覆盖）时为false
+	TransferBlocked bool `json:"transferBlocked,omitempty"`
+	// BlockNumber 该token数据对应的区块号，仅在按token指定区块查询时填充
+	BlockNumber *big.Int `json:"blockNumber,omitempty"`
 }
 
 // QueryResult 表示查询结果
 type QueryResult struct {
-	QueryAddress common.Address
-	Tokens       []TokenInfo
-	Timestamp    *big.Int
-	BlockNumber  *big.Int
+	// SchemaVersion JSON表示的版本，见 ResultSchemaVersion；序列化时总是写出当前版本，
+	// 反序列化后为数据中记录的版本，没有该字段的旧快照为0
+	SchemaVersion int            `json:"schemaVersion"`
+	QueryAddress  common.Address `json:"queryAddress"`
+	// ContractAddress 实际返回该结果的查询合约地址，配置了 WithFallbackContracts 时可能是备用合约
+	ContractAddress common.Address `json:"contractAddress,omitempty"`
+	// ENSName 查询地址经过正反向校验的ENS名称，仅在启用 WithENSNames 时填充，没有名称时为空
+	ENSName     string      `json:"ensName,omitempty"`
+	Tokens      []TokenInfo `json:"tokens"`
+	Timestamp   *big.Int    `json:"timestamp"`
+	BlockNumber *big.Int    `json:"blockNumber"`
+	// BaseFee 查询区块的basefee，仅在启用 WithBaseFee 时填充
+	BaseFee *big.Int `json:"baseFee,omitempty"`
+	// ReorgDetected 分批查询时各批次落在不同区块上，结果不是同一区块的原子快照
+	// 仅在 ReorgWarn 策略下可能为true，默认策略会重新查询使各批次对齐
+	ReorgDetected bool `json:"reorgDetected,omitempty"`
+	// Failures 启用 WithPerTokenFallback 时查询失败的token，这些token不会出现在Tokens中
+	Failures []TokenFailure `json:"failures,omitempty"`
 }
 
+// TokenFailure 单个token查询失败的原因
+type TokenFailure struct {
+	Token common.Address
+	Err   error
+}
+
+// multiTokenQueryABI MultiTokenQuery 合约ABI，需与部署的合约保持一致
+const multiTokenQueryABI = `[{"inputs":[{"internalType":"address","name":"user","type":"address"},{"internalType":"address[]","name":"tokenAddresses","type":"address[]"}],"name":"queryMultipleTokens","outputs":[{"components":[{"internalType":"address","name":"queryAddress","type":"address"},{"components":[{"internalType":"address","name":"tokenAddress","type":"address"},{"internalType":"string","name":"symbol","type":"string"},{"internalType":"uint8","name":"decimals","type":"uint8"},{"internalType":"uint256","name":"balance","type":"uint256"}],"internalType":"struct MultiTokenQuery.TokenInfo[]","name":"tokens","type":"tuple[]"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"uint256","name":"blockNumber","type":"uint256"}],"internalType":"struct MultiTokenQuery.QueryResult","name":"result","type":"tuple"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"user","type":"address"},{"internalType":"address[]","name":"tokenAddresses","type":"address[]"}],"name":"queryBalances","outputs":[{"internalType":"uint256[]","name":"balances","type":"uint256[]"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"uint256","name":"blockNumber","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"user","type":"address"},{"internalType":"address","name":"tokenAddress","type":"address"}],"name":"querySingleToken","outputs":[{"components":[{"internalType":"address","name":"tokenAddress","type":"address"},{"internalType":"string","name":"symbol","type":"string"},{"internalType":"uint8","name":"decimals","type":"uint8"},{"internalType":"uint256","name":"balance","type":"uint256"}],"internalType":"struct MultiTokenQuery.TokenInfo","name":"tokenInfo","type":"tuple"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"uint256","name":"blockNumber","type":"uint256"}],"stateMutability":"view","type":"function"}]`
+
 // MultiTokenQueryClient 多token查询客户端
 type MultiTokenQueryClient struct {
 	client          *ethclient.Client
 	contractAddress common.Address
 	contract        *bind.BoundContract
+	abi             abi.ABI
+
+	withBaseFee       bool
+	retry             *RetryPolicy
+	transportRetry    *RetryPolicy
+	concurrency       int
+	chunkSize         int
+	chunkParallelism  int
+	reorgPolicy       ReorgPolicy
+	denylist          map[common.Address]bool
+	allowlist         map[common.Address]bool
+	stateOverride     StateOverride
+	perTokenFallback  bool
+	blockPollInterval time.Duration
+	poolSize          int
+	tracer            Tracer
+	refresh           *RefreshCall
+	registry          *MetadataRegistry
+	decimalsFallback  *MetadataRegistry
+	confirmationDepth int
+	logger            *slog.Logger
+	redactAddress     func(common.Address) string
+	fallbackContracts []common.Address
+	contractAddrs     []common.Address
+	activeContract    atomic.Int32
+	pending           bool
+	pinSnapshot       bool
+	maxDecimals       uint8
+	totalSupply       bool
+	transferProbe     bool
+	splitDepth        int
+	accessList        types.AccessList
+	abiJSON           string
+	callTimeout       time.Duration
+	scaledTimeout     *ScaledTimeout
+	limiter           *rateLimiter
+	chainID           *big.Int
+	adaptive          *aimdLimiter
+	middlewares       []Middleware
+	callChain         CallFunc
+	probedChunkSize   atomic.Int64
+	onTiming          func(CallTiming)
+	onWarmProgress    func(done, total int)
+	logRange          uint64
+	breakerThreshold  int
+	breakerCooldown   time.Duration
+	shareConverters   map[common.Address]func(shares *big.Int) *big.Int
+	blockTimes        blockTimeCache
+	cache             *resultCache
+	replicaURLs       []string
+	ensNames          bool
+	ensRegistry       common.Address
+	ens               ensCache
+	requestLog        *requestLog
+	costs             *costMeter
+
+	// closeCtx 在 Close 时取消，用于中断其他goroutine中尚未返回的调用
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+	conns       []*poolConn
+	next        atomic.Uint64
+	// replicas WithReadReplicas 配置的只读副本，合约调用优先发往这些连接
+	replicas    []*poolConn
+	nextReplica atomic.Uint64
 }
 
 // NewMultiTokenQueryClient 创建新的查询客户端
-func NewMultiTokenQueryClient(rpcURL string, contractAddress common.Address) (*MultiTokenQueryClient, error) {
-	client, err := ethclient.Dial(rpcURL)
-	if err != nil {
-		return nil, fmt.Errorf("连接以太坊节点失败: %v", err)
+func NewMultiTokenQueryClient(rpcURL string, contractAddress common.Address, opts ...Option) (*MultiTokenQueryClient, error) {
+	return newMultiTokenQueryClient([]string{rpcURL}, contractAddress, opts)
+}
+
+// newMultiTokenQueryClient 按rpcURLs轮流建立 WithPoolSize 个连接（至少每个地址一个）
+func newMultiTokenQueryClient(rpcURLs []string, contractAddress common.Address, opts []Option) (*MultiTokenQueryClient, error) {
+	if len(rpcURLs) == 0 {
+		return nil, fmt.Errorf("至少需要一个节点地址")
 	}
 
-	// 这里需要替换为实际的合约ABI
-	contractABI := `[{"inputs":[{"internalType":"address","name":"user","type":"address"},{"internalType":"address[]","name":"tokenAddresses","type":"address[]"}],"name":"queryMultipleTokens","outputs":[{"components":[{"internalType":"address","name":"queryAddress","type":"address"},{"components":[{"internalType":"address","name":"tokenAddress","type":"address"},{"internalType":"string","name":"symbol","type":"string"},{"internalType":"uint8","name":"decimals","type":"uint8"},{"internalType":"uint256","name":"balance","type":"uint256"}],"internalType":"struct MultiTokenQuery.TokenInfo[]","name":"tokens","type":"tuple[]"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"uint256","name":"blockNumber","type":"uint256"}],"internalType":"struct MultiTokenQuery.QueryResult","name":"result","type":"tuple"}],"stateMutability":"view","type":"function"}]`
+	c := &MultiTokenQueryClient{
+		contractAddress:   contractAddress,
+		concurrency:       defaultConcurrency,
+		blockPollInterval: defaultBlockPollInterval,
+		poolSize:          1,
+		logger:            discardLogger(),
+		maxDecimals:       defaultMaxDecimals,
+		abiJSON:           multiTokenQueryABI,
+		logRange:          defaultLogRange,
+	}
+	c.closeCtx, c.closeCancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.contractAddrs = append([]common.Address{contractAddress}, c.fallbackContracts...)
+	c.callChain = c.buildCallChain()
+	if c.adaptive != nil {
+		c.adaptive.init(c.concurrency)
+		c.adaptive.onChange = func(limit int) {
+			c.logger.Debug("调整批量查询并发数", "limit", limit)
+		}
+	}
 
-	parsedABI, err := abi.JSON(strings.NewReader(contractABI))
+	parsedABI, err := abi.JSON(strings.NewReader(c.abiJSON))
 	if err != nil {
+		c.closeCancel()
 		return nil, fmt.Errorf("解析合约ABI失败: %v", err)
 	}
+	c.abi = parsedABI
+
+	size := c.poolSize
+	if size < len(rpcURLs) {
+		size = len(rpcURLs)
+	}
+	for i := 0; i < size; i++ {
+		conn, err := c.newPoolConn(rpcURLs[i%len(rpcURLs)])
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.conns = append(c.conns, conn)
+	}
+	for _, readURL := range c.replicaURLs {
+		conn, err := c.newPoolConn(readURL)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.replicas = append(c.replicas, conn)
+	}
+
+	c.client = c.conns[0].client
+	c.contract = c.conns[0].contracts[0]
 
-	contract := bind.NewBoundContract(contractAddress, parsedABI, client, client, client)
+	if c.chainID != nil {
+		if err := c.checkChainID(); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
 
-	return &MultiTokenQueryClient{
-		client:          client,
-		contractAddress: contractAddress,
-		contract:        contract,
-	}, nil
+	return c, nil
+}
+
+// newPoolConn 连接rpcURL并绑定所有合约地址
+func (c *MultiTokenQueryClient) newPoolConn(rpcURL string) (*poolConn, error) {
+	client, err := c.dial(rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("连接以太坊节点%s失败: %w", RedactRPCURL(rpcURL), err)
+	}
+	conn := &poolConn{client: client}
+	if c.breakerThreshold > 0 {
+		conn.breaker = newCircuitBreaker(c.breakerThreshold, c.breakerCooldown)
+	}
+	for _, addr := range c.contractAddrs {
+		conn.contracts = append(conn.contracts, bind.NewBoundContract(addr, c.abi, rawCaptureCaller{client}, client, client))
+	}
+	return conn, nil
+}
+
+// dial 连接以太坊节点
+// 启用连接池时每个连接使用独立的HTTP Transport，启用 WithTransportRetry 时为HTTP传输层加上重试
+func (c *MultiTokenQueryClient) dial(rpcURL string) (*ethclient.Client, error) {
+	var transport http.RoundTripper = http.DefaultTransport
+	if c.poolSize > 1 || c.transportRetry != nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	// 计费在重试之内，每次重试都单独计入
+	if c.costs != nil {
+		transport = &costTransport{base: transport, meter: c.costs}
+	}
+	if c.transportRetry != nil {
+		transport = newRetryTransport(transport, *c.transportRetry)
+	}
+
+	rpcClient, err := dialRPC(rpcURL, transport)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(rpcClient), nil
+}
+
+// Close 关闭所有底层的以太坊节点连接
+// 进行中的合约调用会被立即取消并返回 ErrClientClosed，直接发往节点的请求（区块头、代码、日志等）同样会被取消
+func (c *MultiTokenQueryClient) Close() {
+	c.closeCancel()
+	for _, conn := range c.conns {
+		conn.client.Close()
+	}
+	for _, conn := range c.replicas {
+		conn.client.Close()
+	}
+}
+
+// EthClient 返回底层的 ethclient.Client，用于发起本包没有封装的调用（如 eth_getLogs），复用同一个连接
+// 返回的客户端归查询客户端所有，调用方不要Close它；启用连接池时返回第一个连接。
+// 查询客户端 Close 之后返回nil
+func (c *MultiTokenQueryClient) EthClient() *ethclient.Client {
+	if c.closeCtx.Err() != nil {
+		return nil
+	}
+	return c.client
+}
+
+// QueryMethodSignature 返回 QueryMultipleTokens 实际调用的合约方法签名及其4字节选择器
+// 用于排查ABI与部署合约不一致的问题，例如 "queryMultipleTokens(address,address[])"
+func (c *MultiTokenQueryClient) QueryMethodSignature() (name string, selector [4]byte) {
+	method, ok := c.abi.Methods["queryMultipleTokens"]
+	if !ok {
+		return "", selector
+	}
+	copy(selector[:], method.ID)
+	return method.Sig, selector
 }
 
 // QueryMultipleTokens 查询多个token的信息
-func (c *MultiTokenQueryClient) QueryMultipleTokens(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+func (c *MultiTokenQueryClient) QueryMultipleTokens(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) (queryResult *QueryResult, err error) {
+	ctx, end := c.startSpan(ctx, "QueryMultipleTokens", userAddress, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(resultBlock(queryResult), err) }()
+
+	opts, err := c.latestOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.cache != nil && !opts.Pending {
+		if _, pinned := snapshotBlock(ctx); !pinned {
+			return c.queryWithCache(opts, userAddress, tokenAddresses)
+		}
+	}
+	return c.queryResultAt(opts, userAddress, tokenAddresses)
+}
+
+// queryWithCache 只查询缓存中没有或已过期的token，再与缓存合并
+func (c *MultiTokenQueryClient) queryWithCache(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+	hits, misses := c.cache.lookup(userAddress, tokenAddresses)
+	var fresh *QueryResult
+	if len(misses) > 0 || len(tokenAddresses) == 0 {
+		var err error
+		fresh, err = c.queryResultAt(opts, userAddress, misses)
+		if err != nil {
+			return nil, err
+		}
+		c.cache.store(fresh)
+	}
+	merged := c.cache.merge(userAddress, tokenAddresses, fresh, hits)
+	// 全部命中缓存时没有经过 finishResult，ENS名称单独填充（解析结果本身有缓存）
+	if c.ensNames && fresh == nil {
+		c.fillENSName(opts.Context, merged)
+	}
+	return merged, nil
+}
+
+// queryResultAt 按opts查询完整的 QueryResult，并补齐区块号、过滤token、填充basefee
+func (c *MultiTokenQueryClient) queryResultAt(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+	opts = withContractCapture(opts)
+	var queryResult *QueryResult
+	var err error
+	if c.registry != nil {
+		queryResult, err = c.queryWithRegistry(opts, userAddress, tokenAddresses)
+	} else {
+		queryResult, err = c.queryMultipleTokens(opts, userAddress, tokenAddresses)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.finishResult(opts, queryResult); err != nil {
+		return nil, err
+	}
+	return queryResult, nil
+}
+
+// finishResult 对解码后的结果做统一的后处理：补齐区块号、过滤token、份额换算、标记异常decimals、填充totalSupply和basefee
+func (c *MultiTokenQueryClient) finishResult(opts *bind.CallOpts, queryResult *QueryResult) error {
+	if err := c.ensureBlockNumber(opts, queryResult); err != nil {
+		return err
+	}
+	c.fillContractAddress(opts, queryResult)
+	c.filterTokens(queryResult)
+	c.convertShares(queryResult)
+	c.flagSuspiciousDecimals(queryResult)
+
+	if c.totalSupply {
+		if err := c.fillTotalSupply(opts, queryResult); err != nil {
+			return err
+		}
+	}
+	if c.ensNames {
+		c.fillENSName(opts.Context, queryResult)
+	}
+	if c.transferProbe {
+		if err := c.probeTransfers(opts, queryResult); err != nil {
+			return err
+		}
+	}
+	return c.fillBaseFee(opts, queryResult)
+}
+
+// queryMultipleTokensOnce 用一次合约调用查询全部token
+func (c *MultiTokenQueryClient) queryMultipleTokensOnce(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
 	var result []interface{}
-	err := c.contract.Call(&bind.CallOpts{Context: ctx}, &result, "queryMultipleTokens", userAddress, tokenAddresses)
+	err := c.call(opts, &result, "queryMultipleTokens", userAddress, tokenAddresses)
 	if err != nil {
-		return nil, fmt.Errorf("调用合约失败: %v", err)
+		return nil, fmt.Errorf("调用合约失败: %w", err)
 	}
 
 	// 解析返回结果
 	if len(result) == 0 {
-		return nil, fmt.Errorf("合约返回结果为空")
+		return nil, decodeFailure("合约返回结果为空")
+	}
+
+	// 合约返回单个 QueryResult tuple，解码为匿名struct后映射到 QueryResult
+	queryResult, err := decodeQueryResult(result[0])
+	if err != nil {
+		return nil, decodeFailure("解析合约返回结果失败: %v", err)
+	}
+
+	// 合约有bug时返回的token数量可能与请求不一致，按下标对应会错位
+	if len(queryResult.Tokens) != len(tokenAddresses) {
+		return nil, decodeFailure("合约返回的token数量与请求不一致: 请求%d个，返回%d个", len(tokenAddresses), len(queryResult.Tokens))
+	}
+
+	return queryResult, nil
+}
+
+// ensureBlockNumber 保证结果带有数据实际对应的区块号
+// 合约路径由合约返回 block.number，其他路径（或合约未返回）时用固定的区块号或通过 BlockNumber 补齐
+func (c *MultiTokenQueryClient) ensureBlockNumber(opts *bind.CallOpts, result *QueryResult) error {
+	if result.BlockNumber != nil && result.BlockNumber.Sign() > 0 {
+		return nil
+	}
+
+	pinned, err := c.resolvePinnedOpts(opts)
+	if err != nil {
+		return err
+	}
+	result.BlockNumber = pinned.BlockNumber
+	return nil
+}
+
+// fillBaseFee 在启用 WithBaseFee 时查询区块头并填充basefee
+// pending查询的区块尚未产生，取pending区块头
+func (c *MultiTokenQueryClient) fillBaseFee(opts *bind.CallOpts, result *QueryResult) error {
+	if !c.withBaseFee {
+		return nil
 	}
 
-	// 这里需要根据实际的ABI结构来解析结果
-	// 简化示例，实际使用时需要正确解析struct
-	return &QueryResult{
-		QueryAddress: userAddress,
-		// ... 其他字段需要从result中解析
-	}, nil
+	// BlockNumber 为空时查询最新区块
+	number := result.BlockNumber
+	if opts.Pending {
+		number = big.NewInt(int64(rpc.PendingBlockNumber))
+	}
+	ctx, stop := c.closeAware(opts.Context)
+	defer stop()
+	header, err := c.client.HeaderByNumber(ctx, number)
+	if err != nil {
+		return fmt.Errorf("查询区块头失败: %v", err)
+	}
+
+	// London升级之前的区块没有basefee，此时保持为nil
+	result.BaseFee = header.BaseFee
+	return nil
 }
 
 // QueryBalances 简化版本：只查询余额
-func (c *MultiTokenQueryClient) QueryBalances(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) ([]*big.Int, *big.Int, *big.Int, error) {
+func (c *MultiTokenQueryClient) QueryBalances(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) (balances []*big.Int, timestamp *big.Int, blockNumber *big.Int, err error) {
+	ctx, end := c.startSpan(ctx, "QueryBalances", userAddress, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(blockNumber, err) }()
+
+	opts, err := c.latestOpts(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return c.queryBalances(opts, userAddress, tokenAddresses)
+}
+
+// queryBalancesOnce 用一次合约调用查询全部余额
+func (c *MultiTokenQueryClient) queryBalancesOnce(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address) ([]*big.Int, *big.Int, *big.Int, error) {
 	var result []interface{}
-	err := c.contract.Call(&bind.CallOpts{Context: ctx}, &result, "queryBalances", userAddress, tokenAddresses)
+	err := c.call(opts, &result, "queryBalances", userAddress, tokenAddresses)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("调用合约失败: %v", err)
+		return nil, nil, nil, fmt.Errorf("调用合约失败: %w", err)
+	}
+
+	if len(result) != 3 {
+		return nil, nil, nil, fmt.Errorf("合约返回结果数量错误: %d", len(result))
 	}
 
 	// 解析返回的余额数组、时间戳和区块号
@@ -95,9 +472,38 @@ func (c *MultiTokenQueryClient) QueryBalances(ctx context.Context, userAddress c
 	timestamp := result[1].(*big.Int)
 	blockNumber := result[2].(*big.Int)
 
+	// 余额按下标与请求的token对应，数量不一致时无法正确对应
+	if len(balances) != len(tokenAddresses) {
+		return nil, nil, nil, fmt.Errorf("合约返回的余额数量与请求不一致: 请求%d个，返回%d个", len(tokenAddresses), len(balances))
+	}
+
 	return balances, timestamp, blockNumber, nil
 }
 
+// QuickBalances 一次性查询：连接节点、查询余额后立即关闭连接
+// 每次调用都会重新建立连接，重复查询时应复用 MultiTokenQueryClient
+func QuickBalances(ctx context.Context, rpcURL string, contract, user common.Address, tokens []common.Address) ([]*big.Int, error) {
+	// 调用方未设置超时时使用默认的30秒
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+	}
+
+	client, err := NewMultiTokenQueryClient(rpcURL, contract)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	balances, _, _, err := client.QueryBalances(ctx, user, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
 // 使用示例
 func ExampleUsage() {
 	// 连接到以太坊主网或测试网
@@ -132,7 +538,11 @@ func ExampleUsage() {
 	fmt.Printf("用户地址: %s\n", userAddress.Hex())
 	fmt.Printf("时间戳: %s\n", timestamp.String())
 	fmt.Printf("区块号: %s\n", blockNumber.String())
-	fmt.Printf("查询时间: %s\n", time.Unix(timestamp.Int64(), 0).Format("2006-01-02 15:04:05"))
+	if queryTime, ok := timestampToTime(timestamp); ok {
+		fmt.Printf("查询时间: %s\n", queryTime.Format("2006-01-02 15:04:05"))
+	} else {
+		fmt.Printf("查询时间: 无效的时间戳\n")
+	}
 
 	for i, balance := range balances {
 		fmt.Printf("Token %d (%s): %s\n", i+1, tokenAddresses[i].Hex(), balance.String())
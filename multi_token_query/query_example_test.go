@@ -0,0 +1,47 @@
+package contracts
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// dropLastToken 让假节点上的查询合约少返回最后一个token，模拟有bug的合约
+func dropLastToken(node *fakeNode) func(to common.Address, data []byte) ([]byte, error) {
+	return func(to common.Address, data []byte) ([]byte, error) {
+		if to != fakeContract {
+			return nil, nil
+		}
+		method, err := node.abi.MethodById(data[:4])
+		if err != nil || method.Name == "querySingleToken" {
+			return nil, nil
+		}
+		args, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		tokens := args[1].([]common.Address)
+		packed, err := node.abi.Pack(method.Name, args[0], tokens[:len(tokens)-1])
+		if err != nil {
+			return nil, err
+		}
+		return node.callContract(packed)
+	}
+}
+
+func TestShortTokenArrayRejected(t *testing.T) {
+	client, node := newFakeClient(t)
+	tokens := node.addTokens(3)
+	node.setHook(dropLastToken(node))
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	ctx := context.Background()
+
+	if _, err := client.QueryMultipleTokens(ctx, user, tokens); err == nil || !strings.Contains(err.Error(), "请求3个，返回2个") {
+		t.Errorf("QueryMultipleTokens 错误 = %v，期望包含两个数量", err)
+	}
+	if _, _, _, err := client.QueryBalances(ctx, user, tokens); err == nil || !strings.Contains(err.Error(), "请求3个，返回2个") {
+		t.Errorf("QueryBalances 错误 = %v，期望包含两个数量", err)
+	}
+}
@@ -0,0 +1,121 @@
+package contracts
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ResultSchemaVersion QueryResult JSON表示的当前版本
+//
+// 版本策略：JSON中的字段被删除、改名或含义改变时版本号加1，并在 UnmarshalJSON 中把旧版本的数据转换为当前结构；
+// 只新增可选（omitempty）字段时旧数据按缺省值解码即可，不改变版本号。
+// 各版本：
+//   - 0：引入版本号之前写出的快照，没有schemaVersion字段，字段与版本1相同
+//   - 1：增加schemaVersion字段
+const ResultSchemaVersion = 1
+
+// MarshalJSON 实现 json.Marshaler，查询地址和合约地址输出为EIP-55校验和格式，便于审计时直接比对
+// schemaVersion 总是写为 ResultSchemaVersion
+func (r QueryResult) MarshalJSON() ([]byte, error) {
+	type plain QueryResult
+	// 外层字段覆盖plain中的同名字段，放在前面以保持原有的字段顺序
+	out := struct {
+		SchemaVersion   int    `json:"schemaVersion"`
+		QueryAddress    string `json:"queryAddress"`
+		ContractAddress string `json:"contractAddress,omitempty"`
+		plain
+	}{SchemaVersion: ResultSchemaVersion, QueryAddress: r.QueryAddress.Hex(), plain: plain(r)}
+	if r.ContractAddress != (common.Address{}) {
+		out.ContractAddress = r.ContractAddress.Hex()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，接受 ResultSchemaVersion 及之前各版本的数据，
+// 数据的版本比当前程序支持的更新时返回错误，避免静默丢失新版本的字段
+func (r *QueryResult) UnmarshalJSON(data []byte) error {
+	type plain QueryResult
+	var in plain
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.SchemaVersion < 0 || in.SchemaVersion > ResultSchemaVersion {
+		return fmt.Errorf("不支持的结果版本%d，当前支持到版本%d", in.SchemaVersion, ResultSchemaVersion)
+	}
+	// 版本0与版本1的字段相同，无需转换
+	*r = QueryResult(in)
+	return nil
+}
+
+// tokenFailureJSON TokenFailure 的JSON表示，错误序列化为字符串
+type tokenFailureJSON struct {
+	Token common.Address `json:"token"`
+	Error string         `json:"error"`
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (f TokenFailure) MarshalJSON() ([]byte, error) {
+	out := tokenFailureJSON{Token: f.Token}
+	if f.Err != nil {
+		out.Error = f.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，错误还原为只包含原始信息的error
+func (f *TokenFailure) UnmarshalJSON(data []byte) error {
+	var in tokenFailureJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	f.Token = in.Token
+	f.Err = nil
+	if in.Error != "" {
+		f.Err = errors.New(in.Error)
+	}
+	return nil
+}
+
+// WriteResultsJSONGz 以gzip压缩的换行分隔JSON（每行一个 QueryResult）写出结果
+// 逐条编码写入，不会在内存中缓存全部JSON，适合归档大批量的每日快照
+func WriteResultsJSONGz(w io.Writer, results []*QueryResult) error {
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+	for i, result := range results {
+		if err := enc.Encode(result); err != nil {
+			gz.Close()
+			return fmt.Errorf("编码第%d个结果失败: %v", i, err)
+		}
+	}
+	return gz.Close()
+}
+
+// ReadResultsJSONGz 流式读取 WriteResultsJSONGz 写出的数据，每解码一条结果调用一次fn
+// fn 返回错误时停止读取并返回该错误
+func ReadResultsJSONGz(r io.Reader, fn func(*QueryResult) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("读取gzip数据失败: %v", err)
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(gz))
+	for i := 0; ; i++ {
+		var result QueryResult
+		if err := dec.Decode(&result); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("解码第%d个结果失败: %v", i, err)
+		}
+		if err := fn(&result); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package contracts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// fakeContract 测试用查询合约的部署地址
+var fakeContract = common.HexToAddress("0x00000000000000000000000000000000000c0de0")
+
+// fakeToken 假节点上一个token的状态
+type fakeToken struct {
+	symbol   string
+	decimals uint8
+	balance  *big.Int
+}
+
+// fakeNode 进程内的假以太坊节点
+//
+// 通过 rpc.Server 注册eth服务，查询合约的方法按ABI编码返回 tokens 中的数据，
+// token合约本身的 balanceOf/symbol/decimals 也可以直接调用。每次 eth_call 前先执行 hook，
+// hook 返回非nil的数据或错误时直接作为响应，用于模拟revert、异常返回值等情况
+type fakeNode struct {
+	abi     abi.ABI
+	head    uint64
+	latency atomic.Int64 // 每次 eth_call 的延迟，纳秒
+
+	mu     sync.Mutex
+	tokens map[common.Address]fakeToken
+	hook   func(to common.Address, data []byte) ([]byte, error)
+	// codeErr 不为nil时 eth_getCode 返回该错误
+	codeErr error
+
+	calls atomic.Int64
+}
+
+func newFakeNode() *fakeNode {
+	parsed, err := abi.JSON(strings.NewReader(multiTokenQueryABI))
+	if err != nil {
+		panic(err)
+	}
+	return &fakeNode{abi: parsed, head: 18000000, tokens: map[common.Address]fakeToken{}}
+}
+
+// addTokens 添加n个18位小数的token并返回它们的地址
+func (n *fakeNode) addTokens(count int) []common.Address {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	addrs := make([]common.Address, count)
+	for i := range addrs {
+		addrs[i] = common.BigToAddress(big.NewInt(int64(0x10000 + len(n.tokens))))
+		n.tokens[addrs[i]] = fakeToken{symbol: fmt.Sprintf("T%d", len(n.tokens)), decimals: 18, balance: big.NewInt(int64(i + 1))}
+	}
+	return addrs
+}
+
+func (n *fakeNode) setToken(addr common.Address, token fakeToken) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.tokens[addr] = token
+}
+
+func (n *fakeNode) setHook(hook func(to common.Address, data []byte) ([]byte, error)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.hook = hook
+}
+
+func (n *fakeNode) setCodeError(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.codeErr = err
+}
+
+func (n *fakeNode) setLatency(d time.Duration) {
+	n.latency.Store(int64(d))
+}
+
+// start 启动HTTP服务，测试结束时关闭
+func (n *fakeNode) start(tb testing.TB) string {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &fakeEth{node: n}); err != nil {
+		tb.Fatal(err)
+	}
+	srv := httptest.NewServer(server)
+	tb.Cleanup(func() {
+		srv.Close()
+		server.Stop()
+	})
+	return srv.URL
+}
+
+// newFakeClient 启动假节点并创建连接到它的客户端
+func newFakeClient(tb testing.TB, opts ...Option) (*MultiTokenQueryClient, *fakeNode) {
+	tb.Helper()
+	node := newFakeNode()
+	client, err := NewMultiTokenQueryClient(node.start(tb), fakeContract, opts...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(client.Close)
+	return client, node
+}
+
+// fakeRPCError 带错误码和data的JSON-RPC错误
+type fakeRPCError struct {
+	code int
+	msg  string
+	data interface{}
+}
+
+func (e *fakeRPCError) Error() string          { return e.msg }
+func (e *fakeRPCError) ErrorCode() int         { return e.code }
+func (e *fakeRPCError) ErrorData() interface{} { return e.data }
+
+// fakeEth 假节点的eth命名空间
+type fakeEth struct {
+	node *fakeNode
+}
+
+type fakeCallArgs struct {
+	To    *common.Address `json:"to"`
+	Data  hexutil.Bytes   `json:"data"`
+	Input hexutil.Bytes   `json:"input"`
+}
+
+func (e *fakeEth) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(1))
+}
+
+func (e *fakeEth) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(e.node.head)
+}
+
+func (e *fakeEth) GetCode(addr common.Address, block rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	e.node.mu.Lock()
+	defer e.node.mu.Unlock()
+	if e.node.codeErr != nil {
+		return nil, e.node.codeErr
+	}
+	if _, ok := e.node.tokens[addr]; ok || addr == fakeContract {
+		return hexutil.Bytes{0x60, 0x80}, nil
+	}
+	return hexutil.Bytes{}, nil
+}
+
+func (e *fakeEth) GetBlockByNumber(number rpc.BlockNumber, full bool) *types.Header {
+	n := e.node.head
+	if number >= 0 {
+		n = uint64(number)
+	}
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(n),
+		Time:       1700000000 + n*12,
+		Difficulty: new(big.Int),
+		BaseFee:    big.NewInt(30e9),
+	}
+}
+
+func (e *fakeEth) Call(ctx context.Context, args fakeCallArgs, block rpc.BlockNumberOrHash, overrides *json.RawMessage) (hexutil.Bytes, error) {
+	e.node.calls.Add(1)
+	if d := time.Duration(e.node.latency.Load()); d > 0 {
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	data := args.Input
+	if len(data) == 0 {
+		data = args.Data
+	}
+	if args.To == nil || len(data) < 4 {
+		return nil, &fakeRPCError{code: -32602, msg: "invalid call"}
+	}
+
+	e.node.mu.Lock()
+	hook := e.node.hook
+	e.node.mu.Unlock()
+	if hook != nil {
+		if out, err := hook(*args.To, data); out != nil || err != nil {
+			return out, err
+		}
+	}
+
+	if *args.To == fakeContract {
+		return e.node.callContract(data)
+	}
+	return e.node.callToken(*args.To, data)
+}
+
+type fakeTokenInfo struct {
+	TokenAddress common.Address
+	Symbol       string
+	Decimals     uint8
+	Balance      *big.Int
+}
+
+type fakeQueryResult struct {
+	QueryAddress common.Address
+	Tokens       []fakeTokenInfo
+	Timestamp    *big.Int
+	BlockNumber  *big.Int
+}
+
+// callContract 按ABI解码查询合约的调用并编码返回值
+func (n *fakeNode) callContract(data []byte) ([]byte, error) {
+	method, err := n.abi.MethodById(data[:4])
+	if err != nil {
+		return nil, &fakeRPCError{code: RPCCodeExecutionReverted, msg: "execution reverted"}
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := new(big.Int).SetUint64(1700000000 + n.head*12)
+	blockNumber := new(big.Int).SetUint64(n.head)
+	user := args[0].(common.Address)
+	switch method.Name {
+	case "queryMultipleTokens":
+		result := fakeQueryResult{QueryAddress: user, Timestamp: timestamp, BlockNumber: blockNumber}
+		for _, addr := range args[1].([]common.Address) {
+			result.Tokens = append(result.Tokens, n.tokenInfo(addr))
+		}
+		return method.Outputs.Pack(result)
+	case "queryBalances":
+		tokens := args[1].([]common.Address)
+		balances := make([]*big.Int, len(tokens))
+		for i, addr := range tokens {
+			balances[i] = n.tokenInfo(addr).Balance
+		}
+		return method.Outputs.Pack(balances, timestamp, blockNumber)
+	case "querySingleToken":
+		return method.Outputs.Pack(n.tokenInfo(args[1].(common.Address)), timestamp, blockNumber)
+	}
+	return nil, &fakeRPCError{code: RPCCodeExecutionReverted, msg: "execution reverted"}
+}
+
+func (n *fakeNode) tokenInfo(addr common.Address) fakeTokenInfo {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	token, ok := n.tokens[addr]
+	if !ok {
+		return fakeTokenInfo{TokenAddress: addr, Balance: new(big.Int)}
+	}
+	return fakeTokenInfo{TokenAddress: addr, Symbol: token.symbol, Decimals: token.decimals, Balance: token.balance}
+}
+
+// callToken 直接调用token合约的 balanceOf/symbol/decimals
+func (n *fakeNode) callToken(addr common.Address, data []byte) ([]byte, error) {
+	n.mu.Lock()
+	token, ok := n.tokens[addr]
+	n.mu.Unlock()
+	if !ok {
+		return hexutil.Bytes{}, nil
+	}
+
+	selector := data[:4]
+	switch {
+	case bytes.Equal(selector, selectorBalanceOf):
+		return common.LeftPadBytes(token.balance.Bytes(), 32), nil
+	case bytes.Equal(selector, selectorDecimals):
+		return common.LeftPadBytes([]byte{token.decimals}, 32), nil
+	case bytes.Equal(selector, selectorSymbol):
+		stringType, _ := abi.NewType("string", "", nil)
+		return abi.Arguments{{Type: stringType}}.Pack(token.symbol)
+	}
+	return nil, &fakeRPCError{code: RPCCodeExecutionReverted, msg: "execution reverted"}
+}
+
+func TestFakeNodeQueryMultipleTokens(t *testing.T) {
+	client, node := newFakeClient(t)
+	tokens := node.addTokens(3)
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	result, err := client.QueryMultipleTokens(context.Background(), user, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Tokens) != 3 || result.Tokens[2].Balance.Int64() != 3 || result.BlockNumber.Uint64() != node.head {
+		t.Fatalf("结果不符合预期: %+v", result)
+	}
+}
@@ -0,0 +1,126 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// WithPerTokenFallback 批量调用被revert（通常是某个token的调用revert导致整个调用失败）或返回结果无法解码时
+// 退回到逐个token调用 querySingleToken，能查到的token正常返回，
+// 失败的token记录在 QueryResult.Failures 中，而不是让整个查询失败。
+// 网络、限流、超时等与具体token无关的错误不会退回，逐个调用只会放大请求量，直接返回原错误。
+// querySingleToken 也被revert的token会绕过查询合约直接调用token合约，balanceOf 不返回数据的非标准token
+// 按余额0返回并标记 TokenInfo.EmptyBalanceData；读取decimals失败的处理见 WithDecimalsFallback
+func WithPerTokenFallback() Option {
+	return func(c *MultiTokenQueryClient) {
+		c.perTokenFallback = true
+	}
+}
+
+// queryMultipleTokensChunk 查询一个批次，响应超出大小限制时按 WithAdaptiveSplit 拆分，
+// 启用 WithPerTokenFallback 时在revert或解码失败后逐个token查询
+func (c *MultiTokenQueryClient) queryMultipleTokensChunk(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+	result, err := c.queryMultipleTokensSplit(opts, userAddress, tokenAddresses, 0)
+	if err == nil || !c.perTokenFallback || !(errors.Is(err, ErrRevert) || isDecodeError(err)) {
+		return result, err
+	}
+
+	return c.queryPerToken(opts, userAddress, tokenAddresses)
+}
+
+// queryPerToken 逐个token并发调用 querySingleToken，保持tokenAddresses的顺序，被revert的token再绕过查询合约读取
+// 查询最新状态时先解析出当前区块号并固定，保证各token的数据来自同一区块
+func (c *MultiTokenQueryClient) queryPerToken(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+	opts, err := c.resolvePinnedOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*TokenInfo, len(tokenAddresses))
+	timestamps := make([]*big.Int, len(tokenAddresses))
+	errs := make([]error, len(tokenAddresses))
+
+	runBounded(c.concurrency, len(tokenAddresses), func(i int) {
+		info, timestamp, _, err := c.querySingleToken(opts, userAddress, tokenAddresses[i])
+		if errors.Is(err, ErrRevert) {
+			info, timestamp, err = c.queryTokenFallback(opts, userAddress, tokenAddresses[i], err)
+		}
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		infos[i], timestamps[i] = info, timestamp
+	})
+
+	result := &QueryResult{QueryAddress: userAddress, BlockNumber: opts.BlockNumber}
+	for i, info := range infos {
+		if info == nil {
+			result.Failures = append(result.Failures, TokenFailure{Token: tokenAddresses[i], Err: errs[i]})
+			continue
+		}
+		result.Tokens = append(result.Tokens, *info)
+		if result.Timestamp == nil {
+			result.Timestamp = timestamps[i]
+		}
+	}
+
+	// 所有token都是直接调用token合约读取的，没有合约返回的时间戳，从区块头补齐
+	if result.Timestamp == nil && len(result.Tokens) > 0 {
+		number := opts.BlockNumber
+		if opts.Pending {
+			number = big.NewInt(int64(rpc.PendingBlockNumber))
+		}
+		ctx, stop := c.closeAware(opts.Context)
+		header, err := c.client.HeaderByNumber(ctx, number)
+		stop()
+		if err != nil {
+			return nil, fmt.Errorf("查询区块头失败: %v", err)
+		}
+		result.Timestamp = new(big.Int).SetUint64(header.Time)
+	}
+
+	return result, nil
+}
+
+// decodeError 合约调用成功但返回结果无法解码或与请求不一致
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string { return e.err.Error() }
+
+func (e *decodeError) Unwrap() error { return e.err }
+
+// decodeFailure 按 fmt.Errorf 的格式构造 decodeError
+func decodeFailure(format string, args ...interface{}) error {
+	return &decodeError{err: fmt.Errorf(format, args...)}
+}
+
+func isDecodeError(err error) bool {
+	var decodeErr *decodeError
+	return errors.As(err, &decodeErr)
+}
+
+// querySingleToken 调用合约的 querySingleToken 查询单个token
+func (c *MultiTokenQueryClient) querySingleToken(opts *bind.CallOpts, userAddress, tokenAddress common.Address) (*TokenInfo, *big.Int, *big.Int, error) {
+	var result []interface{}
+	if err := c.call(opts, &result, "querySingleToken", userAddress, tokenAddress); err != nil {
+		return nil, nil, nil, fmt.Errorf("调用合约失败: %w", err)
+	}
+	if len(result) != 3 {
+		return nil, nil, nil, decodeFailure("合约返回结果数量错误: %d", len(result))
+	}
+
+	info, err := decodeTokenInfo(reflect.ValueOf(result[0]))
+	if err != nil {
+		return nil, nil, nil, decodeFailure("解析合约返回结果失败: %v", err)
+	}
+
+	return &info, result[1].(*big.Int), result[2].(*big.Int), nil
+}
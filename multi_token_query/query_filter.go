@@ -0,0 +1,60 @@
+package contracts
+
+import "github.com/ethereum/go-ethereum/common"
+
+// WithDenylist 从 QueryResult.Tokens 中剔除名单内的token（如空投的垃圾/诈骗token）
+// 过滤在解码之后进行，合约调用本身不受影响
+func WithDenylist(denylist map[common.Address]bool) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.denylist = denylist
+	}
+}
+
+// WithAllowlist 只保留名单内的token，其余全部剔除
+// 与 WithDenylist 同时使用时，先按白名单保留，再按黑名单剔除
+func WithAllowlist(allowlist map[common.Address]bool) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.allowlist = allowlist
+	}
+}
+
+// filterTokens 按黑白名单过滤查询结果中的token
+func (c *MultiTokenQueryClient) filterTokens(result *QueryResult) {
+	if c.allowlist == nil && c.denylist == nil {
+		return
+	}
+
+	kept := result.Tokens[:0]
+	for _, token := range result.Tokens {
+		if c.allowlist != nil && !c.allowlist[token.TokenAddress] {
+			continue
+		}
+		if c.denylist[token.TokenAddress] {
+			continue
+		}
+		kept = append(kept, token)
+	}
+	result.Tokens = kept
+}
+
+// defaultMaxDecimals 正常token的decimals上限，超过即视为可疑
+const defaultMaxDecimals = 36
+
+// WithMaxDecimals 设置可疑decimals的阈值，decimals大于该值的token会被标记 SuspiciousDecimals
+func WithMaxDecimals(max uint8) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.maxDecimals = max
+	}
+}
+
+// flagSuspiciousDecimals 标记decimals异常大的token并输出警告，不会剔除这些token
+func (c *MultiTokenQueryClient) flagSuspiciousDecimals(result *QueryResult) {
+	for i := range result.Tokens {
+		token := &result.Tokens[i]
+		if token.Decimals <= c.maxDecimals && !token.DecimalsClamped {
+			continue
+		}
+		token.SuspiciousDecimals = true
+		c.logger.Warn("token的decimals异常", "token", token.TokenAddress.Hex(), "symbol", token.Symbol, "decimals", token.Decimals)
+	}
+}
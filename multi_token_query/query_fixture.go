@@ -0,0 +1,144 @@
+package contracts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TokenQuerier 余额查询的公共接口，*MultiTokenQueryClient、*AlchemyTokenClient 和 *FixtureClient 都实现了它，
+// 业务代码依赖该接口即可在测试和本地开发中换成固定数据
+type TokenQuerier interface {
+	QueryMultipleTokens(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error)
+	QueryBalances(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) ([]*big.Int, *big.Int, *big.Int, error)
+}
+
+var (
+	_ TokenQuerier = (*MultiTokenQueryClient)(nil)
+	_ TokenQuerier = (*FixtureClient)(nil)
+)
+
+// ErrFixtureNotFound 固定数据中没有与查询的用户和token集合匹配的条目
+var ErrFixtureNotFound = errors.New("没有匹配的固定数据")
+
+// FixtureEntry 固定数据文件中的一条记录：用户在某个token集合上的查询结果
+// Tokens 为空时使用 Result 中的token地址
+type FixtureEntry struct {
+	User   common.Address   `json:"user"`
+	Tokens []common.Address `json:"tokens,omitempty"`
+	Result *QueryResult     `json:"result"`
+}
+
+// FixtureClient 从JSON固定数据返回查询结果，不连接任何节点，用于离线开发界面和可复现的测试
+//
+// 固定数据文件是 FixtureEntry 的JSON数组，可以把真实查询的 QueryResult 直接序列化后写入生成。
+// 按（用户，token集合）匹配，token的顺序无关，返回结果中token按请求的顺序排列；没有匹配的条目时返回 ErrFixtureNotFound
+type FixtureClient struct {
+	entries map[string]*QueryResult
+}
+
+// NewFixtureClient 读取固定数据文件
+func NewFixtureClient(path string) (*FixtureClient, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开固定数据文件失败: %v", err)
+	}
+	defer f.Close()
+	return ReadFixtureClient(f)
+}
+
+// ReadFixtureClient 从r读取固定数据
+func ReadFixtureClient(r io.Reader) (*FixtureClient, error) {
+	var entries []FixtureEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("解析固定数据失败: %v", err)
+	}
+
+	f := &FixtureClient{entries: make(map[string]*QueryResult, len(entries))}
+	for i, entry := range entries {
+		if entry.Result == nil {
+			return nil, fmt.Errorf("第%d条固定数据缺少result", i)
+		}
+		tokens := entry.Tokens
+		if len(tokens) == 0 {
+			for _, token := range entry.Result.Tokens {
+				tokens = append(tokens, token.TokenAddress)
+			}
+		}
+		f.entries[fixtureKey(entry.User, tokens)] = entry.Result
+	}
+	return f, nil
+}
+
+// fixtureKey 用户地址加排序后的token地址
+func fixtureKey(user common.Address, tokens []common.Address) string {
+	sorted := append([]common.Address(nil), tokens...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+
+	var b strings.Builder
+	b.WriteString(user.Hex())
+	for _, token := range sorted {
+		b.WriteByte(',')
+		b.WriteString(token.Hex())
+	}
+	return b.String()
+}
+
+// QueryMultipleTokens 返回匹配的固定结果的副本
+func (f *FixtureClient) QueryMultipleTokens(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fixture, ok := f.entries[fixtureKey(userAddress, tokenAddresses)]
+	if !ok {
+		return nil, fmt.Errorf("%w: 用户%s，%d个token", ErrFixtureNotFound, userAddress.Hex(), len(tokenAddresses))
+	}
+
+	result := *fixture
+	byAddress := make(map[common.Address]TokenInfo, len(fixture.Tokens))
+	for _, token := range fixture.Tokens {
+		byAddress[token.TokenAddress] = token
+	}
+	result.Tokens = make([]TokenInfo, 0, len(tokenAddresses))
+	for _, addr := range tokenAddresses {
+		if token, ok := byAddress[addr]; ok {
+			result.Tokens = append(result.Tokens, cloneTokenInfo(token))
+		}
+	}
+	// 大整数逐个复制，调用方修改返回的结果不会影响后续调用
+	result.Timestamp = cloneBig(fixture.Timestamp)
+	result.BlockNumber = cloneBig(fixture.BlockNumber)
+	result.BaseFee = cloneBig(fixture.BaseFee)
+	result.Failures = append([]TokenFailure(nil), fixture.Failures...)
+	return &result, nil
+}
+
+// QueryBalances 按请求顺序返回匹配的固定结果中的余额，固定结果中没有的token余额为nil
+func (f *FixtureClient) QueryBalances(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) ([]*big.Int, *big.Int, *big.Int, error) {
+	result, err := f.QueryMultipleTokens(ctx, userAddress, tokenAddresses)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	byAddress := make(map[common.Address]*big.Int, len(result.Tokens))
+	for _, token := range result.Tokens {
+		byAddress[token.TokenAddress] = token.Balance
+	}
+	balances := make([]*big.Int, len(tokenAddresses))
+	for i, addr := range tokenAddresses {
+		balances[i] = byAddress[addr]
+	}
+	return balances, result.Timestamp, result.BlockNumber, nil
+}
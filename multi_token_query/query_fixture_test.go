@@ -0,0 +1,35 @@
+package contracts
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestFixtureClientReturnsCopies(t *testing.T) {
+	const data = `[{"user":"0x1111111111111111111111111111111111111111","result":{"queryAddress":"0x1111111111111111111111111111111111111111","tokens":[{"tokenAddress":"0x6b175474e89094c44da98b954eedeac495271d0f","symbol":"DAI","decimals":18,"balance":1000}],"timestamp":1700000000,"blockNumber":18000000}}]`
+	f, err := ReadFixtureClient(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	tokens := []common.Address{common.HexToAddress("0x6b175474e89094c44da98b954eedeac495271d0f")}
+	ctx := context.Background()
+
+	first, err := f.QueryMultipleTokens(ctx, user, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Tokens[0].Balance.SetInt64(1)
+	first.BlockNumber.SetInt64(1)
+
+	second, err := f.QueryMultipleTokens(ctx, user, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Tokens[0].Balance.Int64() != 1000 || second.BlockNumber.Int64() != 18000000 {
+		t.Fatalf("修改第一次的结果影响了固定数据: balance=%s block=%s", second.Tokens[0].Balance, second.BlockNumber)
+	}
+}
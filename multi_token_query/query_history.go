@@ -0,0 +1,149 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BalanceSnapshot 表示某个区块上的余额快照
+type BalanceSnapshot struct {
+	QueryAddress common.Address
+	Tokens       []common.Address
+	Balances     []*big.Int
+	Timestamp    *big.Int
+	BlockNumber  *big.Int
+}
+
+// QueryBalancesAtBlock 查询指定历史区块上的余额，需要归档节点
+// 节点没有该区块的状态时返回的错误满足 errors.Is(err, ErrHistoricalUnsupported)
+func (c *MultiTokenQueryClient) QueryBalancesAtBlock(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address, blockNumber *big.Int) (snapshot *BalanceSnapshot, err error) {
+	ctx, end := c.startSpan(ctx, "QueryBalancesAtBlock", userAddress, SpanAttributes{TokenCount: len(tokenAddresses), BlockNumber: blockNumber})
+	defer func() {
+		var resolved *big.Int
+		if snapshot != nil {
+			resolved = snapshot.BlockNumber
+		}
+		end(resolved, err)
+	}()
+
+	balances, timestamp, resolvedBlock, err := c.queryBalances(&bind.CallOpts{Context: ctx, BlockNumber: blockNumber}, userAddress, tokenAddresses)
+	if err != nil {
+		if isHistoricalStateError(err) {
+			return nil, fmt.Errorf("%w: 区块%v: %v", ErrHistoricalUnsupported, blockNumber, err)
+		}
+		return nil, err
+	}
+
+	return &BalanceSnapshot{
+		QueryAddress: userAddress,
+		Tokens:       tokenAddresses,
+		Balances:     balances,
+		Timestamp:    timestamp,
+		BlockNumber:  resolvedBlock,
+	}, nil
+}
+
+// QueryBalancesAtTx 查询某笔交易所在区块上的余额，即该区块所有交易执行之后的状态，用于交易后的对账
+// 交易不存在（错误满足 errors.Is(err, ethereum.NotFound)）或尚未打包时返回错误；较早的区块需要归档节点
+func (c *MultiTokenQueryClient) QueryBalancesAtTx(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address, txHash common.Hash) (*BalanceSnapshot, error) {
+	receipt, err := c.transactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	return c.QueryBalancesAtBlock(ctx, userAddress, tokenAddresses, receipt.BlockNumber)
+}
+
+// transactionReceipt 查询已打包交易的回执，客户端 Close 时取消
+func (c *MultiTokenQueryClient) transactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	ctx, stop := c.closeAware(ctx)
+	defer stop()
+
+	receipt, err := c.client.TransactionReceipt(ctx, txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		if _, isPending, txErr := c.client.TransactionByHash(ctx, txHash); txErr == nil && isPending {
+			return nil, fmt.Errorf("交易%s尚未打包，没有对应的区块", txHash.Hex())
+		}
+		return nil, fmt.Errorf("交易%s不存在: %w", txHash.Hex(), err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询交易回执失败: %w", err)
+	}
+	if receipt.BlockNumber == nil {
+		return nil, fmt.Errorf("交易%s的回执缺少区块号", txHash.Hex())
+	}
+	return receipt, nil
+}
+
+// QueryBalancesTimeSeries 在多个历史区块上查询同一地址的余额，用于绘制余额曲线
+// 各区块并发查询（受 WithConcurrency 限制），返回的快照与blocks顺序一致。
+// 某个区块查询失败时对应位置为nil，错误中会逐个列出失败的区块
+func (c *MultiTokenQueryClient) QueryBalancesTimeSeries(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address, blocks []*big.Int) (_ []*BalanceSnapshot, err error) {
+	ctx, end := c.startSpan(ctx, "QueryBalancesTimeSeries", userAddress, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(nil, err) }()
+
+	snapshots := make([]*BalanceSnapshot, len(blocks))
+	errs := make([]error, len(blocks))
+
+	runBounded(c.concurrency, len(blocks), func(i int) {
+		snapshot, err := c.QueryBalancesAtBlock(ctx, userAddress, tokenAddresses, blocks[i])
+		if err != nil {
+			errs[i] = fmt.Errorf("区块%v的数据不可用: %w", blocks[i], err)
+			return
+		}
+		snapshots[i] = snapshot
+	})
+
+	return snapshots, errors.Join(errs...)
+}
+
+// QueryMultipleTokensAtBlocks 按token分别指定查询区块，合并为一个 QueryResult
+//
+// blockOverrides 中的token在对应区块上查询，其余token在当前最新区块上查询；
+// 每个token的数据区块记录在 TokenInfo.BlockNumber 中，QueryResult.BlockNumber 为默认的最新区块。
+// 该模式无法使用合约的批量查询，每个token都是一次单独的 querySingleToken 调用，历史区块需要归档节点。
+// 结果与 QueryMultipleTokens 经过相同的后处理；basefee 为默认区块的basefee
+func (c *MultiTokenQueryClient) QueryMultipleTokensAtBlocks(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address, blockOverrides map[common.Address]*big.Int) (*QueryResult, error) {
+	latest, err := c.resolvePinnedOpts(withContractCapture(&bind.CallOpts{Context: ctx}))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]TokenInfo, len(tokenAddresses))
+	errs := make([]error, len(tokenAddresses))
+
+	runBounded(c.concurrency, len(tokenAddresses), func(i int) {
+		opts := latest
+		if block, ok := blockOverrides[tokenAddresses[i]]; ok && block != nil {
+			opts = pinnedOpts(latest, block)
+		}
+
+		info, _, _, err := c.querySingleToken(opts, userAddress, tokenAddresses[i])
+		if err != nil {
+			errs[i] = fmt.Errorf("查询token %s 在区块%v上的数据失败: %w", tokenAddresses[i].Hex(), opts.BlockNumber, err)
+			return
+		}
+		info.BlockNumber = opts.BlockNumber
+		infos[i] = *info
+	})
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	result := &QueryResult{
+		QueryAddress: userAddress,
+		Tokens:       infos,
+		BlockNumber:  latest.BlockNumber,
+	}
+	if err := c.finishResult(latest, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
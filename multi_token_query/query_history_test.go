@@ -0,0 +1,38 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestQueryBalancesAtBlockHistoricalUnsupported(t *testing.T) {
+	messages := []string{
+		"missing trie node 1d0f7b2c3b6a1e4f (path )",
+		"state not available for block 0x112a880",
+		"header not found",
+	}
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	for i, msg := range messages {
+		t.Run(msg, func(t *testing.T) {
+			client, node := newFakeClient(t)
+			tokens := node.addTokens(1)
+			node.setHook(func(common.Address, []byte) ([]byte, error) {
+				return nil, &fakeRPCError{code: -32000, msg: msg}
+			})
+
+			_, err := client.QueryBalancesAtBlock(context.Background(), user, tokens, big.NewInt(1000000))
+			if err == nil {
+				t.Fatal("期望返回错误")
+			}
+			want := i < 2
+			if got := errors.Is(err, ErrHistoricalUnsupported); got != want {
+				t.Fatalf("errors.Is(%v, ErrHistoricalUnsupported) = %v，期望 %v", err, got, want)
+			}
+		})
+	}
+}
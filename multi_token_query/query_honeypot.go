@@ -0,0 +1,116 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var selectorTransfer = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+
+// probeRecipient 模拟转账的接收地址，一个没有私钥的普通地址，避免token对零地址或合约地址的特殊处理
+var probeRecipient = common.HexToAddress("0x000000000000000000000000000000000000bEEF")
+
+// probeGasBalance 模拟转账时覆盖给用户的原生币余额，避免节点因用户没有gas费而拒绝调用
+var probeGasBalance = (*hexutil.Big)(new(big.Int).Lsh(big.NewInt(1), 100))
+
+// WithTransferProbe 查询后对每个余额非零的token模拟一次用户转出全部余额，转账被revert或返回false时
+// 设置 TokenInfo.TransferBlocked，用于在钱包界面上提示只能买不能卖的蜜罐token
+//
+// 每个token额外一次 eth_call（受 WithConcurrency 限制），默认关闭。
+// 模拟依赖 eth_call 的stateOverride参数（为用户覆盖原生币余额以支付gas），geth、erigon、nethermind
+// 以及 Alchemy、Infura 等主流服务商支持，部分公共节点会拒绝或忽略；模拟本身失败时只记录警告，不标记token。
+// 只能发现无条件阻止转账的token，按接收方、时间或金额限制转账的token可能检测不到，结果仅作为风险提示
+func WithTransferProbe() Option {
+	return func(c *MultiTokenQueryClient) {
+		c.transferProbe = true
+	}
+}
+
+// probeTransfers 在结果所在区块模拟各token的转账，ctx取消或客户端关闭时返回错误
+// 模拟调用与合约调用一样经过完整的中间件链（超时、重试、限流、追踪、请求日志以及 WithMiddleware），方法名为transfer
+func (c *MultiTokenQueryClient) probeTransfers(opts *bind.CallOpts, result *QueryResult) error {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	base := c.overridesFor(ctx)
+	overrides := make(StateOverride, len(base)+1)
+	for addr, account := range base {
+		overrides[addr] = account
+	}
+	account := overrides[result.QueryAddress]
+	account.Balance = probeGasBalance
+	overrides[result.QueryAddress] = account
+
+	probeOpts := *opts
+	probeOpts.Context = ctx
+	if !opts.Pending && result.BlockNumber != nil {
+		probeOpts.BlockNumber = result.BlockNumber
+	}
+	chain := chainMiddlewares(c.invokeTransfer(overrides), c.callMiddlewares()...)
+
+	runBounded(c.concurrency, len(result.Tokens), func(i int) {
+		token := &result.Tokens[i]
+		// 份额类token按balanceOf返回的原始份额转账
+		amount := token.Balance
+		if token.Shares != nil {
+			amount = token.Shares
+		}
+		if amount == nil || amount.Sign() <= 0 {
+			return
+		}
+
+		var results []interface{}
+		err := c.callVia(chain, &probeOpts, &results, "transfer", result.QueryAddress, token.TokenAddress, amount)
+		switch {
+		case errors.Is(err, ErrRevert):
+			token.TransferBlocked = true
+		case err != nil:
+			c.logger.Warn("模拟转账失败", "token", token.TokenAddress.Hex(), "error", err)
+		// 不返回数据的非标准token（如USDT）视为转账成功，返回false视为被阻止
+		case transferReturnedFalse(results[0].(hexutil.Bytes)):
+			token.TransferBlocked = true
+		}
+	})
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.closeCtx.Err() != nil {
+		return ErrClientClosed
+	}
+	return nil
+}
+
+// invokeTransfer 返回调用链最内层的模拟转账调用，params 依次为转出的用户、token地址和数量，
+// 结果为 eth_call 返回的原始数据
+func (c *MultiTokenQueryClient) invokeTransfer(overrides StateOverride) CallFunc {
+	return func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+		from, token, amount := params[0].(common.Address), params[1].(common.Address), params[2].(*big.Int)
+		data := append(append(append([]byte{}, selectorTransfer...), common.LeftPadBytes(probeRecipient.Bytes(), 32)...), common.LeftPadBytes(amount.Bytes(), 32)...)
+		msg := map[string]interface{}{
+			"from": from,
+			"to":   token,
+			"data": hexutil.Bytes(data),
+		}
+
+		var output hexutil.Bytes
+		if err := c.conn().client.Client().CallContext(opts.Context, &output, "eth_call", msg, blockArg(opts), overrides); err != nil {
+			return classifyCallError(err)
+		}
+		*results = []interface{}{output}
+		return nil
+	}
+}
+
+// transferReturnedFalse 判断 transfer 是否返回了false，不返回数据视为成功
+func transferReturnedFalse(output hexutil.Bytes) bool {
+	return len(output) >= 32 && new(big.Int).SetBytes(output[:32]).Sign() == 0
+}
@@ -0,0 +1,47 @@
+package contracts
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestProbeTransfersUsesMiddlewareChain(t *testing.T) {
+	var probes atomic.Int64
+	countTransfers := func(next CallFunc) CallFunc {
+		return func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+			if method == "transfer" {
+				probes.Add(1)
+			}
+			return next(opts, results, method, params...)
+		}
+	}
+	client, node := newFakeClient(t, WithTransferProbe(), WithMiddleware(countTransfers))
+	tokens := node.addTokens(2)
+	// 第一个token的转账revert，第二个不返回数据
+	node.setHook(func(to common.Address, data []byte) ([]byte, error) {
+		if !bytes.Equal(data[:4], selectorTransfer) {
+			return nil, nil
+		}
+		if to == tokens[0] {
+			return nil, &fakeRPCError{code: RPCCodeExecutionReverted, msg: "execution reverted"}
+		}
+		return []byte{}, nil
+	})
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	result, err := client.QueryMultipleTokens(context.Background(), user, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := probes.Load(); got != 2 {
+		t.Errorf("中间件看到 %d 次transfer调用，期望2次", got)
+	}
+	if !result.Tokens[0].TransferBlocked || result.Tokens[1].TransferBlocked {
+		t.Errorf("TransferBlocked = %v/%v，期望 true/false", result.Tokens[0].TransferBlocked, result.Tokens[1].TransferBlocked)
+	}
+}
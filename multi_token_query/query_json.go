@@ -0,0 +1,246 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// JSONNaming JSON字段名的命名风格
+type JSONNaming int
+
+const (
+	// JSONCamelCase 小驼峰，如 tokenAddress，与结构体上的json tag一致（默认）
+	JSONCamelCase JSONNaming = iota
+	// JSONSnakeCase 下划线，如 token_address
+	JSONSnakeCase
+	// JSONPascalCase 大驼峰，如 TokenAddress
+	JSONPascalCase
+)
+
+// AddressKeyCase TokensByAddress 输出时作为键的地址格式
+type AddressKeyCase int
+
+const (
+	// AddressKeyChecksum EIP-55校验和格式，如 0xA0b8...eB48（默认）
+	AddressKeyChecksum AddressKeyCase = iota
+	// AddressKeyLower 全小写，如 0xa0b8...eb48
+	AddressKeyLower
+	// AddressKeyBoth 每个token在校验和与小写两个键下各出现一次，供按不同格式索引的消费方直接使用
+	AddressKeyBoth
+)
+
+// JSONOptions 控制 MarshalJSONWith 输出的字段名
+type JSONOptions struct {
+	// Naming 命名风格
+	Naming JSONNaming
+	// Rename 按默认（小驼峰）字段名重命名个别字段，如 {"balance": "amount"}，
+	// 优先于 Naming，重命名后的名字原样输出
+	Rename map[string]string
+	// TokensByAddress 为true时结果的tokens输出为以token地址为键的对象而不是数组，键的格式由 AddressKeys 决定
+	TokensByAddress bool
+	// AddressKeys TokensByAddress 时键的地址格式，默认校验和格式
+	AddressKeys AddressKeyCase
+}
+
+// key 返回默认字段名在该选项下的输出名
+func (o JSONOptions) key(name string) string {
+	if renamed, ok := o.Rename[name]; ok {
+		return renamed
+	}
+	switch o.Naming {
+	case JSONSnakeCase:
+		return camelToSnake(name)
+	case JSONPascalCase:
+		if name == "" {
+			return name
+		}
+		return strings.ToUpper(name[:1]) + name[1:]
+	}
+	return name
+}
+
+// MarshalJSONWith 按指定的选项序列化结果，嵌套的token和失败信息一并重命名
+// 不带选项的 json.Marshal 仍输出默认的小驼峰字段名和token数组
+func (r *QueryResult) MarshalJSONWith(opts JSONOptions) ([]byte, error) {
+	data, err := marshalJSONWith(r, opts)
+	if err != nil || !opts.TokensByAddress {
+		return data, err
+	}
+
+	// 先按选项序列化各token，再整体替换tokens字段，避免地址键被字段命名规则改写
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	seen := make(map[string]bool, len(r.Tokens))
+	for _, token := range r.Tokens {
+		entry, err := marshalJSONWith(token, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range opts.addressKeys(token.TokenAddress) {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(&buf, "%q:", key)
+			buf.Write(entry)
+		}
+	}
+	buf.WriteByte('}')
+	return replaceJSONField(data, opts.key("tokens"), buf.Bytes())
+}
+
+// addressKeys 返回地址在该选项下作为键的形式
+func (o JSONOptions) addressKeys(addr common.Address) []string {
+	switch o.AddressKeys {
+	case AddressKeyLower:
+		return []string{strings.ToLower(addr.Hex())}
+	case AddressKeyBoth:
+		return []string{addr.Hex(), strings.ToLower(addr.Hex())}
+	}
+	return []string{addr.Hex()}
+}
+
+// replaceJSONField 把JSON对象data中键为key的字段值替换为value，其余字段及顺序不变
+func replaceJSONField(data []byte, key string, value []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("替换JSON字段失败: 不是JSON对象")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; dec.More(); i++ {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("替换JSON字段失败: %v", err)
+		}
+		name, _ := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("替换JSON字段失败: %v", err)
+		}
+		if name == key {
+			raw = value
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		b, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+		buf.WriteByte(':')
+		buf.Write(raw)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalJSONWith 按指定的字段命名方式序列化单个token
+func (t TokenInfo) MarshalJSONWith(opts JSONOptions) ([]byte, error) {
+	return marshalJSONWith(t, opts)
+}
+
+// marshalJSONWith 先按默认tag序列化，再逐个token改写对象的键，保持字段原有顺序
+func marshalJSONWith(v interface{}, opts JSONOptions) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return renameJSONKeys(data, opts.key)
+}
+
+// renameJSONKeys 改写JSON中所有对象的键，其余内容原样保留
+func renameJSONKeys(data []byte, rename func(string) string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// frame 记录当前所在的容器，n 为已写出的元素数（对象中键和值分别计数）
+	type frame struct {
+		object bool
+		n      int
+	}
+	var stack []frame
+	var buf bytes.Buffer
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("改写JSON字段名失败: %v", err)
+		}
+
+		isKey := false
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if d, ok := tok.(json.Delim); !ok || (d != '}' && d != ']') {
+				switch {
+				case top.object && top.n%2 == 1:
+					buf.WriteByte(':')
+				case top.n > 0:
+					buf.WriteByte(',')
+				}
+				isKey = top.object && top.n%2 == 0
+				top.n++
+			}
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			buf.WriteByte(byte(t))
+			switch t {
+			case '{', '[':
+				stack = append(stack, frame{object: t == '{'})
+			default:
+				stack = stack[:len(stack)-1]
+			}
+		case json.Number:
+			buf.WriteString(t.String())
+		case string:
+			if isKey {
+				t = rename(t)
+			}
+			b, err := json.Marshal(t)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		default:
+			b, err := json.Marshal(t)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// camelToSnake 把小驼峰字段名转换为下划线形式，如 blockNumber -> block_number
+func camelToSnake(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package contracts
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WithLogger 设置日志输出，默认不输出任何日志
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *MultiTokenQueryClient) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithAddressRedactor 设置钱包地址的脱敏函数
+// 钱包地址出现在日志、错误信息和追踪属性之前都会经过该函数，token合约地址是公开信息，不做处理。
+// 默认不脱敏，可直接使用 RedactAddress
+func WithAddressRedactor(redact func(common.Address) string) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.redactAddress = redact
+	}
+}
+
+// RedactAddress 只保留地址的前4位和后4位十六进制字符，如 0x742d…d8b6
+func RedactAddress(addr common.Address) string {
+	hex := addr.Hex()
+	return hex[:6] + "…" + hex[len(hex)-4:]
+}
+
+// walletLabel 返回用于日志和错误信息的钱包地址
+func (c *MultiTokenQueryClient) walletLabel(addr common.Address) string {
+	if c.redactAddress == nil {
+		return addr.Hex()
+	}
+	return c.redactAddress(addr)
+}
+
+// discardLogger 默认的日志输出，丢弃所有日志
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
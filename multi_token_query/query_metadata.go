@@ -0,0 +1,138 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TokenMetadata token的元数据
+type TokenMetadata struct {
+	Symbol   string
+	Decimals uint8
+}
+
+// MetadataRegistry 地址到token元数据的注册表，并发安全
+// 配置到客户端后，已登记的token不再从链上读取symbol/decimals
+type MetadataRegistry struct {
+	mu     sync.RWMutex
+	tokens map[common.Address]TokenMetadata
+}
+
+// NewMetadataRegistry 创建空的元数据注册表
+func NewMetadataRegistry() *MetadataRegistry {
+	return &MetadataRegistry{tokens: make(map[common.Address]TokenMetadata)}
+}
+
+// Set 登记或覆盖token的元数据
+func (r *MetadataRegistry) Set(token common.Address, metadata TokenMetadata) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[token] = metadata
+}
+
+// Get 查找token的元数据
+func (r *MetadataRegistry) Get(token common.Address) (TokenMetadata, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	metadata, ok := r.tokens[token]
+	return metadata, ok
+}
+
+// tokenList Uniswap token list（tokenlists.org）格式
+type tokenList struct {
+	Tokens []struct {
+		ChainID  int64          `json:"chainId"`
+		Address  common.Address `json:"address"`
+		Symbol   string         `json:"symbol"`
+		Decimals uint8          `json:"decimals"`
+	} `json:"tokens"`
+}
+
+// LoadTokenList 从 Uniswap token list 格式的JSON中导入chainID链上的token，返回导入的数量
+func (r *MetadataRegistry) LoadTokenList(rd io.Reader, chainID int64) (int, error) {
+	var list tokenList
+	if err := json.NewDecoder(rd).Decode(&list); err != nil {
+		return 0, fmt.Errorf("解析token列表失败: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, token := range list.Tokens {
+		if token.ChainID != chainID {
+			continue
+		}
+		r.tokens[token.Address] = TokenMetadata{Symbol: token.Symbol, Decimals: token.Decimals}
+		n++
+	}
+	return n, nil
+}
+
+// WithMetadataRegistry 查询时优先使用注册表中的元数据
+// 已登记的token只通过 queryBalances 读取余额，未登记的token仍走完整查询
+func WithMetadataRegistry(registry *MetadataRegistry) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.registry = registry
+	}
+}
+
+// queryWithRegistry 已登记的token只查余额，未登记的token完整查询，两部分固定在同一区块后按原顺序合并
+func (c *MultiTokenQueryClient) queryWithRegistry(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+	var known, unknown []common.Address
+	for _, token := range tokenAddresses {
+		if _, ok := c.registry.Get(token); ok {
+			known = append(known, token)
+		} else {
+			unknown = append(unknown, token)
+		}
+	}
+	if len(known) == 0 {
+		return c.queryMultipleTokens(opts, userAddress, tokenAddresses)
+	}
+
+	result := &QueryResult{QueryAddress: userAddress}
+	infos := make(map[common.Address]TokenInfo, len(tokenAddresses))
+
+	if len(unknown) > 0 {
+		partial, err := c.queryMultipleTokens(opts, userAddress, unknown)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range partial.Tokens {
+			infos[info.TokenAddress] = info
+		}
+		result.Failures = partial.Failures
+		result.ReorgDetected = partial.ReorgDetected
+		if partial.BlockNumber != nil {
+			opts = pinnedOpts(opts, partial.BlockNumber)
+		}
+	}
+
+	balances, timestamp, blockNumber, err := c.queryBalances(opts, userAddress, known)
+	if err != nil {
+		return nil, err
+	}
+	result.Timestamp, result.BlockNumber = timestamp, blockNumber
+	for i, token := range known {
+		metadata, _ := c.registry.Get(token)
+		infos[token] = TokenInfo{
+			TokenAddress: token,
+			Symbol:       metadata.Symbol,
+			Decimals:     metadata.Decimals,
+			Balance:      balances[i],
+		}
+	}
+
+	for _, token := range tokenAddresses {
+		if info, ok := infos[token]; ok {
+			result.Tokens = append(result.Tokens, info)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+package contracts
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// CallFunc 一次合约调用，签名与 bind.BoundContract.Call 相同
+type CallFunc func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error
+
+// Middleware 包装一次合约调用，可以在调用前后加入自定义逻辑，或者不调用next直接返回
+type Middleware func(next CallFunc) CallFunc
+
+// WithMiddleware 为每次合约调用加入自定义中间件，按传入顺序由外到内执行
+//
+// 自定义中间件位于内置中间件之外，每次逻辑调用只经过一次；内置中间件由内到外依次为
+// 耗时记录（WithCallTiming）、追踪（WithTracer）、限流（Config.RateLimit）、重试（WithRetry）、超时（Config.CallTimeout 或 WithScaledTimeout）和请求日志（WithRequestLog），
+// 未启用的功能不会加入调用链。多次调用 WithMiddleware 时依次追加
+func WithMiddleware(mws ...Middleware) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// chainMiddlewares 用mws由外到内包装base
+func chainMiddlewares(base CallFunc, mws ...Middleware) CallFunc {
+	for i := len(mws) - 1; i >= 0; i-- {
+		base = mws[i](base)
+	}
+	return base
+}
+
+// buildCallChain 按客户端配置组装调用链，最内层为 invoke（含备用合约的切换）
+func (c *MultiTokenQueryClient) buildCallChain() CallFunc {
+	return chainMiddlewares(c.invoke, c.callMiddlewares()...)
+}
+
+// callMiddlewares 按客户端配置返回调用链上的全部中间件，由外到内
+func (c *MultiTokenQueryClient) callMiddlewares() []Middleware {
+	mws := append([]Middleware{}, c.middlewares...)
+	if c.requestLog != nil {
+		mws = append(mws, c.requestLogMiddleware())
+	}
+	if c.scaledTimeout != nil {
+		mws = append(mws, ScaledTimeoutMiddleware(*c.scaledTimeout))
+	} else if c.callTimeout > 0 {
+		mws = append(mws, TimeoutMiddleware(c.callTimeout))
+	}
+	if c.retry != nil {
+		mws = append(mws, retryMiddleware(*c.retry, c.isRetryable, c.logger))
+	}
+	if c.limiter != nil {
+		mws = append(mws, rateLimitMiddleware(c.limiter))
+	}
+	if c.tracer != nil {
+		mws = append(mws, TracingMiddleware(c.tracer))
+	}
+	if c.onTiming != nil {
+		mws = append(mws, TimingMiddleware(c.onTiming))
+	}
+	return mws
+}
+
+// TimeoutMiddleware 为被包装的调用（包括其内层的全部重试）设置超时
+func TimeoutMiddleware(d time.Duration) Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+			ctx, cancel := withCallTimeout(opts.Context, d)
+			defer cancel()
+			bound := *opts
+			bound.Context = ctx
+			return next(&bound, results, method, params...)
+		}
+	}
+}
+
+// callTimeoutKey 在ctx中记录超时中间件设置的单次调用超时，用于区分调用方结束和单次调用超时
+type callTimeoutKey struct{}
+
+// callTimeout 单次调用超时的时长和设置超时之前的ctx
+type callTimeout struct {
+	parent context.Context
+	d      time.Duration
+}
+
+// withCallTimeout 与 context.WithTimeout 相同，同时记下parent和d供 callerDone、renewCallTimeout 使用
+func withCallTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, d)
+	return context.WithValue(ctx, callTimeoutKey{}, callTimeout{parent: parent, d: d}), cancel
+}
+
+// callerDone 判断调用方的ctx是否已经结束（取消、到期或客户端关闭）
+// 超时中间件设置的单次调用超时到期而调用方的ctx仍然有效时返回false，说明是节点太慢
+func callerDone(ctx context.Context) bool {
+	if t, ok := ctx.Value(callTimeoutKey{}).(callTimeout); ok {
+		return t.parent.Err() != nil
+	}
+	return ctx.Err() != nil
+}
+
+// renewCallTimeout 为同一次调用改发其他节点时重新计时单次调用超时
+// 新ctx保留ctx中的值（追踪span等），调用方的ctx结束时随之取消；ctx没有经过超时中间件时原样返回
+func renewCallTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	t, ok := ctx.Value(callTimeoutKey{}).(callTimeout)
+	if !ok {
+		return ctx, func() {}
+	}
+	renewed, cancel := context.WithTimeout(context.WithoutCancel(ctx), t.d)
+	stop := context.AfterFunc(t.parent, cancel)
+	return renewed, func() {
+		stop()
+		cancel()
+	}
+}
+
+// RateLimitMiddleware 限制每秒发起的调用次数，burst 为允许的瞬时突发数（小于1时按1处理）
+// 每次调用前等待限额，超出时等待而不是报错；等待时间超过ctx的截止时间时立即返回错误
+func RateLimitMiddleware(perSecond float64, burst int) Middleware {
+	return rateLimitMiddleware(newRateLimiter(perSecond, burst))
+}
+
+func rateLimitMiddleware(limiter *rateLimiter) Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+			if err := limiter.Wait(opts.Context); err != nil {
+				return err
+			}
+			return next(opts, results, method, params...)
+		}
+	}
+}
+
+// TracingMiddleware 为每次调用开始一个名为 "eth_call <method>" 的span
+func TracingMiddleware(t Tracer) Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+			ctx, end := t.StartSpan(opts.Context, "eth_call "+method, SpanAttributes{BlockNumber: opts.BlockNumber})
+			traced := *opts
+			traced.Context = ctx
+
+			err := next(&traced, results, method, params...)
+			end(opts.BlockNumber, err)
+			return err
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package contracts
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// openMetricsEscaper 转义OpenMetrics标签值中的反斜杠、双引号和换行
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteResultsOpenMetrics 以OpenMetrics文本格式写出结果，供Prometheus直接抓取，不依赖Prometheus客户端库
+//
+// 输出三个gauge：
+//   - token_balance{wallet,token,symbol} 按decimals换算后的余额
+//   - token_query_block_number{wallet} 结果所在区块
+//   - token_query_timestamp_seconds{wallet} 结果所在区块的时间戳
+//
+// 数值为float64，只有约15到17位有效数字，较大的余额在末几位会丢失精度，只适合监控和告警，不能用于对账。
+// 同一钱包和token重复出现时只输出第一次，避免重复的时间序列；最后写出 "# EOF"
+func WriteResultsOpenMetrics(w io.Writer, results []*QueryResult) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("# TYPE token_balance gauge\n")
+	bw.WriteString("# HELP token_balance Token balance converted by decimals.\n")
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		wallet := result.QueryAddress.Hex()
+		for _, token := range result.Tokens {
+			key := wallet + token.TokenAddress.Hex()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			value, _ := token.TokenAmount().Float64()
+			bw.WriteString(`token_balance{wallet="` + wallet + `",token="` + token.TokenAddress.Hex() + `",symbol="` + openMetricsEscaper.Replace(token.Symbol) + `"} `)
+			bw.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+			bw.WriteByte('\n')
+		}
+	}
+
+	writeResultGauge(bw, results, "token_query_block_number", "Block number of the query result.", func(r *QueryResult) string {
+		if r.BlockNumber == nil {
+			return ""
+		}
+		return r.BlockNumber.String()
+	})
+	writeResultGauge(bw, results, "token_query_timestamp_seconds", "Block timestamp of the query result.", func(r *QueryResult) string {
+		if r.Timestamp == nil {
+			return ""
+		}
+		return r.Timestamp.String()
+	})
+
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+// writeResultGauge 为每个钱包写出一个以wallet为标签的gauge，value 返回空字符串时跳过该结果
+func writeResultGauge(bw *bufio.Writer, results []*QueryResult, name, help string, value func(*QueryResult) string) {
+	bw.WriteString("# TYPE " + name + " gauge\n")
+	bw.WriteString("# HELP " + name + " " + help + "\n")
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		wallet := result.QueryAddress.Hex()
+		v := value(result)
+		if v == "" || seen[wallet] {
+			continue
+		}
+		seen[wallet] = true
+		bw.WriteString(name + `{wallet="` + wallet + `"} ` + v + "\n")
+	}
+}
@@ -0,0 +1,45 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+)
+
+// Option 用于配置 MultiTokenQueryClient 的可选项
+type Option func(*MultiTokenQueryClient)
+
+// WithBaseFee 在查询结果中附带查询区块的basefee
+// 需要额外一次 HeaderByNumber 调用，默认关闭
+func WithBaseFee() Option {
+	return func(c *MultiTokenQueryClient) {
+		c.withBaseFee = true
+	}
+}
+
+// WithConcurrency 设置并发查询（如按区块、按用户批量查询）时的最大并发调用数，默认8
+func WithConcurrency(n int) Option {
+	return func(c *MultiTokenQueryClient) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// checkChainID 检查主连接的链ID
+func (c *MultiTokenQueryClient) checkChainID() error {
+	ctx := context.Background()
+	if c.callTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.callTimeout)
+		defer cancel()
+	}
+
+	id, err := c.client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("查询链ID失败: %w", err)
+	}
+	if id.Cmp(c.chainID) != 0 {
+		return fmt.Errorf("节点链ID为%s，与配置的%s不一致", id, c.chainID)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// OverrideAccount eth_call 状态覆盖中单个账户的覆盖内容，未设置的字段保持链上状态
+type OverrideAccount struct {
+	Nonce   *hexutil.Uint64 `json:"nonce,omitempty"`
+	Code    hexutil.Bytes   `json:"code,omitempty"`
+	Balance *hexutil.Big    `json:"balance,omitempty"`
+	// State 完整替换账户存储，与 StateDiff 互斥
+	State map[common.Hash]common.Hash `json:"state,omitempty"`
+	// StateDiff 只覆盖指定的存储槽
+	StateDiff map[common.Hash]common.Hash `json:"stateDiff,omitempty"`
+}
+
+// StateOverride eth_call 的第三个参数（stateOverride），用于在假设的状态下模拟查询
+type StateOverride map[common.Address]OverrideAccount
+
+// WithStateOverride 在每次合约调用时附带状态覆盖
+//
+// 启用后不再通过 bind 发起调用，而是直接发送 eth_call JSON-RPC 请求。
+// geth、erigon、nethermind 以及 Alchemy、Infura 等主流服务商支持该参数，
+// 部分轻节点和公共节点会忽略或拒绝它，使用前请确认节点支持
+func WithStateOverride(overrides StateOverride) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.stateOverride = overrides
+	}
+}
+
+// invokeContract 在conn上调用第index个合约地址，配置了状态覆盖或访问列表时走底层rpc
+func (c *MultiTokenQueryClient) invokeContract(conn *poolConn, index int, opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+	overrides := c.overridesFor(opts.Context)
+	if overrides == nil && c.accessList == nil {
+		return classifyCallError(conn.contracts[index].Call(opts, results, method, params...))
+	}
+	return classifyCallError(c.callWithOverride(conn, c.contractAddrs[index], opts, results, method, overrides, params...))
+}
+
+// stateOverrideKey 在ctx中保存单次查询的状态覆盖
+type stateOverrideKey struct{}
+
+// overridesFor 返回本次调用生效的状态覆盖：客户端级别的 WithStateOverride 与ctx中单次查询的覆盖合并，
+// 同一账户以单次查询的为准
+func (c *MultiTokenQueryClient) overridesFor(ctx context.Context) StateOverride {
+	var perCall StateOverride
+	if ctx != nil {
+		perCall, _ = ctx.Value(stateOverrideKey{}).(StateOverride)
+	}
+	if len(perCall) == 0 {
+		return c.stateOverride
+	}
+	if len(c.stateOverride) == 0 {
+		return perCall
+	}
+
+	merged := make(StateOverride, len(c.stateOverride)+len(perCall))
+	for addr, account := range c.stateOverride {
+		merged[addr] = account
+	}
+	for addr, account := range perCall {
+		merged[addr] = account
+	}
+	return merged
+}
+
+// SimulateBalances 在pending区块上叠加状态覆盖后查询余额，用于模拟"某笔交易执行之后余额会是多少"
+//
+// overrides 通常由模拟交易的效果构造，例如覆盖token合约中用户余额所在的存储槽（StateDiff），
+// 与 WithStateOverride 配置的覆盖合并，同一账户以overrides为准。返回的结果不对应任何已出块的状态。
+//
+// 强烈依赖节点支持：需要同时支持 eth_call 的stateOverride参数和以pending为区块参数的调用，
+// geth、erigon、nethermind 自建节点通常都支持；许多公共节点和部分服务商不支持pending或会忽略覆盖，
+// 此时结果与普通查询相同或调用直接失败，使用前请在目标节点上验证
+func (c *MultiTokenQueryClient) SimulateBalances(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address, overrides StateOverride) (queryResult *QueryResult, err error) {
+	ctx, end := c.startSpan(ctx, "SimulateBalances", userAddress, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(resultBlock(queryResult), err) }()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opts := &bind.CallOpts{Context: context.WithValue(ctx, stateOverrideKey{}, overrides), Pending: true}
+	return c.queryResultAt(opts, userAddress, tokenAddresses)
+}
+
+// callWithOverride 直接通过 eth_call 调用合约，附带状态覆盖和访问列表（均可为空）
+func (c *MultiTokenQueryClient) callWithOverride(conn *poolConn, contractAddress common.Address, opts *bind.CallOpts, results *[]interface{}, method string, overrides StateOverride, params ...interface{}) error {
+	input, err := c.abi.Pack(method, params...)
+	if err != nil {
+		return err
+	}
+
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	msg := map[string]interface{}{
+		"to":   contractAddress,
+		"data": hexutil.Bytes(input),
+	}
+	if opts.From != (common.Address{}) {
+		msg["from"] = opts.From
+	}
+	if c.accessList != nil {
+		msg["accessList"] = c.accessList
+	}
+
+	args := []interface{}{msg, blockArg(opts)}
+	if overrides != nil {
+		args = append(args, overrides)
+	}
+
+	var output hexutil.Bytes
+	if err := conn.client.Client().CallContext(ctx, &output, "eth_call", args...); err != nil {
+		return err
+	}
+	if len(output) == 0 {
+		return errors.New("合约调用没有返回数据")
+	}
+	captureRaw(ctx, output)
+
+	unpacked, err := c.abi.Unpack(method, output)
+	if err != nil {
+		return err
+	}
+	*results = unpacked
+	return nil
+}
+
+// blockArg 把 CallOpts 转换为JSON-RPC的区块参数，未指定区块时为最新区块
+func blockArg(opts *bind.CallOpts) string {
+	if opts.Pending {
+		return "pending"
+	}
+	if opts.BlockNumber == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(opts.BlockNumber)
+}
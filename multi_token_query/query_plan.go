@@ -0,0 +1,149 @@
+package contracts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// QueryPath 查询每个用户时使用的调用方式
+type QueryPath string
+
+const (
+	// QueryPathContract 每个批次调用一次查询合约的 queryMultipleTokens
+	QueryPathContract QueryPath = "contract"
+	// QueryPathRegistry WithMetadataRegistry 中已登记的token只调用 queryBalances，其余token调用 queryMultipleTokens
+	QueryPathRegistry QueryPath = "contract+registry"
+)
+
+// QueryPlan QueryMultipleTokensBatch 等批量查询的执行计划，由 ExplainPlan 给出
+type QueryPlan struct {
+	Users  int
+	Tokens int
+	Path   QueryPath
+	// RegisteredTokens 按注册表只查询余额的token数，Path 为 QueryPathRegistry 时才大于0
+	RegisteredTokens int
+	// ChunkSize 单次合约调用的token数上限，0表示不拆分；ProbeMaxChunkSize 探测到的值优先
+	ChunkSize int
+	// ChunksPerUser 每个用户拆成的合约调用数
+	ChunksPerUser int
+	// ChunkParallelism 同一用户的批次同时在途的数量，1表示逐批执行
+	ChunkParallelism int
+	// UserConcurrency 同时查询的用户数，启用 WithAdaptiveConcurrency 时为当前调整到的值
+	UserConcurrency     int
+	AdaptiveConcurrency bool
+	// ContractCalls 预计的合约调用总数，不包括重试、响应超限后的拆分和逐个token的退回；
+	// 启用结果缓存时是缓存全部未命中时的上限
+	ContractCalls int
+	// Block 查询的区块："latest"、"latest-N"（WithConfirmationDepth）或 "pending"
+	Block string
+	// ConsistentSnapshot 所有用户固定在开始时解析出的同一个区块上（WithConsistentSnapshot）
+	ConsistentSnapshot bool
+	// Contracts 依次尝试的查询合约地址
+	Contracts        []common.Address
+	PerTokenFallback bool
+	ResultCache      bool
+	// FilteredTokens 查询后会被黑白名单剔除的token数，这些token仍然会被查询
+	FilteredTokens int
+}
+
+// ExplainPlan 返回批量查询users在tokens上的执行计划，类似SQL的EXPLAIN，用于了解一次查询的开销和瓶颈
+// 只根据客户端的配置计算，不发出任何网络请求，也不改变客户端状态；单个用户的 QueryMultipleTokens 即 users 只有一个的情况
+func (c *MultiTokenQueryClient) ExplainPlan(users, tokens []common.Address) QueryPlan {
+	plan := QueryPlan{
+		Users:               len(users),
+		Tokens:              len(tokens),
+		Path:                QueryPathContract,
+		ChunkSize:           c.effectiveChunkSize(),
+		ChunkParallelism:    max(c.chunkParallelism, 1),
+		UserConcurrency:     c.ConcurrencyLimit(),
+		AdaptiveConcurrency: c.adaptive != nil,
+		Block:               "latest",
+		ConsistentSnapshot:  c.pinSnapshot,
+		Contracts:           append([]common.Address(nil), c.contractAddrs...),
+		PerTokenFallback:    c.perTokenFallback,
+		ResultCache:         c.cache != nil,
+	}
+	if c.pending {
+		plan.Block = "pending"
+	} else if c.confirmationDepth > 0 {
+		plan.Block = fmt.Sprintf("latest-%d", c.confirmationDepth)
+	}
+
+	unknown := tokens
+	if c.registry != nil {
+		unknown = nil
+		for _, token := range tokens {
+			if _, ok := c.registry.Get(token); ok {
+				plan.RegisteredTokens++
+			} else {
+				unknown = append(unknown, token)
+			}
+		}
+		if plan.RegisteredTokens > 0 {
+			plan.Path = QueryPathRegistry
+		}
+	}
+
+	plan.ChunksPerUser = planChunks(len(unknown), plan.ChunkSize)
+	if plan.RegisteredTokens > 0 {
+		if len(unknown) == 0 {
+			plan.ChunksPerUser = 0
+		}
+		plan.ChunksPerUser += planChunks(plan.RegisteredTokens, plan.ChunkSize)
+	}
+	plan.ContractCalls = plan.ChunksPerUser * len(users)
+
+	for _, token := range tokens {
+		if (c.allowlist != nil && !c.allowlist[token]) || c.denylist[token] {
+			plan.FilteredTokens++
+		}
+	}
+	return plan
+}
+
+// planChunks 与 chunkTokens 相同的拆分方式下n个token的批次数，空列表也需要一次调用
+func planChunks(n, size int) int {
+	if size <= 0 || n <= size {
+		return 1
+	}
+	return (n + size - 1) / size
+}
+
+// String 以多行文本输出计划，便于打印到日志或终端
+func (p QueryPlan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "path: %s\n", p.Path)
+	fmt.Fprintf(&b, "users: %d, tokens: %d", p.Users, p.Tokens)
+	if p.RegisteredTokens > 0 {
+		fmt.Fprintf(&b, " (%d from registry)", p.RegisteredTokens)
+	}
+	if p.FilteredTokens > 0 {
+		fmt.Fprintf(&b, " (%d filtered after query)", p.FilteredTokens)
+	}
+	b.WriteString("\n")
+	chunkSize := "unlimited"
+	if p.ChunkSize > 0 {
+		chunkSize = fmt.Sprint(p.ChunkSize)
+	}
+	fmt.Fprintf(&b, "chunks per user: %d (chunk size %s, parallelism %d)\n", p.ChunksPerUser, chunkSize, p.ChunkParallelism)
+	concurrency := fmt.Sprint(p.UserConcurrency)
+	if p.AdaptiveConcurrency {
+		concurrency += " (adaptive)"
+	}
+	fmt.Fprintf(&b, "user concurrency: %s\n", concurrency)
+	fmt.Fprintf(&b, "contract calls: %d\n", p.ContractCalls)
+	block := p.Block
+	if p.ConsistentSnapshot {
+		block += " (consistent snapshot)"
+	}
+	fmt.Fprintf(&b, "block: %s\n", block)
+	contracts := make([]string, len(p.Contracts))
+	for i, addr := range p.Contracts {
+		contracts[i] = addr.Hex()
+	}
+	fmt.Fprintf(&b, "contracts: %s\n", strings.Join(contracts, ", "))
+	fmt.Fprintf(&b, "per-token fallback: %t, result cache: %t", p.PerTokenFallback, p.ResultCache)
+	return b.String()
+}
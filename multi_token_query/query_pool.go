@@ -0,0 +1,69 @@
+package contracts
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// defaultConcurrency 默认最大并发调用数
+const defaultConcurrency = 8
+
+// runBounded 以最多limit个并发执行fn(0..n-1)，全部完成后返回
+// fn 需自行记录结果和错误，调用方通过ctx控制提前退出
+func runBounded(limit, n int, fn func(i int)) {
+	if limit <= 0 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// poolConn 连接池中的一个连接
+type poolConn struct {
+	client *ethclient.Client
+	// contracts 与 contractAddrs 一一对应，第0个为主合约
+	contracts []*bind.BoundContract
+	// breaker 启用 WithCircuitBreaker 时的熔断器，否则为nil
+	breaker *circuitBreaker
+}
+
+// WithPoolSize 建立n个底层连接，合约调用在这些连接之间轮询，
+// 提高批量查询在高并发下的吞吐量。默认1，即单连接
+func WithPoolSize(n int) Option {
+	return func(c *MultiTokenQueryClient) {
+		if n > 0 {
+			c.poolSize = n
+		}
+	}
+}
+
+// NewPooledMultiTokenQueryClient 创建连接到多个节点的查询客户端
+// 连接按rpcURLs的顺序轮流建立，数量为 WithPoolSize 与 len(rpcURLs) 中的较大者，
+// 所有节点应属于同一条链
+func NewPooledMultiTokenQueryClient(rpcURLs []string, contractAddress common.Address, opts ...Option) (*MultiTokenQueryClient, error) {
+	return newMultiTokenQueryClient(rpcURLs, contractAddress, opts)
+}
+
+// conn 轮询选出下一个连接
+func (c *MultiTokenQueryClient) conn() *poolConn {
+	if len(c.conns) == 1 {
+		return c.conns[0]
+	}
+	return c.conns[c.next.Add(1)%uint64(len(c.conns))]
+}
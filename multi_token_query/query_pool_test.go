@@ -0,0 +1,37 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BenchmarkPoolSize 比较不同连接池大小下批量查询的吞吐量，假节点每次调用有固定延迟
+func BenchmarkPoolSize(b *testing.B) {
+	users := make([]common.Address, 64)
+	for i := range users {
+		users[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+
+	for _, size := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("pool=%d", size), func(b *testing.B) {
+			client, node := newFakeClient(b, WithPoolSize(size), WithConcurrency(32))
+			tokens := node.addTokens(10)
+			node.setLatency(2 * time.Millisecond)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.QueryMultipleTokensBatch(ctx, users, tokens); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(users))/b.Elapsed().Seconds(), "users/s")
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// positionUser PositionUser 的类型
+type positionUser struct{}
+
+// PositionUser 用在 PositionSpec.Args 中，调用时替换为 QueryPositions 的用户地址
+var PositionUser = positionUser{}
+
+// PositionSpec 描述一个协议仓位的查询：在Contract上以Args调用ABI中的只读方法Method
+//
+//	contracts.PositionSpec{
+//		Name:     "aave-v3",
+//		Contract: aavePool,
+//		ABI:      aavePoolABI,
+//		Method:   "getUserAccountData",
+//		Args:     []interface{}{contracts.PositionUser},
+//	}
+type PositionSpec struct {
+	// Name 仓位名称，原样带到结果中，便于调用方区分
+	Name     string
+	Contract common.Address
+	ABI      abi.ABI
+	Method   string
+	// Args 方法参数，类型须与ABI一致；PositionUser 会替换为用户地址
+	Args []interface{}
+}
+
+// PositionResult 单个仓位的查询结果
+type PositionResult struct {
+	Name     string
+	Contract common.Address
+	Method   string
+	// Values 按ABI解码出的返回值，与方法的输出一一对应
+	Values []interface{}
+	// Named 按输出名称索引的返回值，未命名的输出使用 output0、output1……
+	Named map[string]interface{}
+	// Err 该仓位查询失败的原因，成功时为nil
+	Err error
+}
+
+// QueryPositions 查询一组协议仓位，把单合约的余额查询推广到任意合约的只读方法
+//
+// 各仓位并发查询（受 WithConcurrency 限制），固定在同一区块，并经过与余额查询相同的调用链
+// （自定义中间件、超时、重试、限流、追踪）；查询合约的备用地址和状态覆盖不适用于这里。
+// 单个仓位失败记录在对应结果的Err中，只有无法确定查询区块时才返回错误。返回的结果与specs顺序一致
+func (c *MultiTokenQueryClient) QueryPositions(ctx context.Context, userAddress common.Address, specs []PositionSpec) (results []PositionResult, err error) {
+	ctx, end := c.startSpan(ctx, "QueryPositions", userAddress, SpanAttributes{})
+	var block *big.Int
+	defer func() { end(block, err) }()
+
+	opts, err := c.latestOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts, err = c.resolvePinnedOpts(opts); err != nil {
+		return nil, err
+	}
+	block = opts.BlockNumber
+
+	results = make([]PositionResult, len(specs))
+	runBounded(c.concurrency, len(specs), func(i int) {
+		results[i] = c.queryPosition(opts, userAddress, specs[i])
+	})
+	return results, nil
+}
+
+// queryPosition 查询单个仓位
+func (c *MultiTokenQueryClient) queryPosition(opts *bind.CallOpts, userAddress common.Address, spec PositionSpec) PositionResult {
+	result := PositionResult{Name: spec.Name, Contract: spec.Contract, Method: spec.Method}
+
+	method, ok := spec.ABI.Methods[spec.Method]
+	if !ok {
+		result.Err = fmt.Errorf("ABI中没有方法%s", spec.Method)
+		return result
+	}
+
+	args := make([]interface{}, len(spec.Args))
+	for i, arg := range spec.Args {
+		if _, ok := arg.(positionUser); ok {
+			arg = userAddress
+		}
+		args[i] = arg
+	}
+
+	if c.closeCtx.Err() != nil {
+		result.Err = ErrClientClosed
+		return result
+	}
+	ctx, stop := c.closeAware(opts.Context)
+	defer stop()
+	bound := *opts
+	bound.Context = ctx
+
+	base := func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+		contract := bind.NewBoundContract(spec.Contract, spec.ABI, rawCaptureCaller{c.conn().client}, nil, nil)
+		return classifyCallError(contract.Call(opts, results, method, params...))
+	}
+	call := chainMiddlewares(base, c.callMiddlewares()...)
+
+	var values []interface{}
+	if err := call(&bound, &values, spec.Method, args...); err != nil {
+		result.Err = fmt.Errorf("查询仓位%s失败: %w", spec.Name, err)
+		return result
+	}
+
+	result.Values = values
+	result.Named = make(map[string]interface{}, len(values))
+	for i, value := range values {
+		name := fmt.Sprintf("output%d", i)
+		if i < len(method.Outputs) && method.Outputs[i].Name != "" {
+			name = method.Outputs[i].Name
+		}
+		result.Named[name] = value
+	}
+	return result
+}
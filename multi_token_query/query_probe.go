@@ -0,0 +1,86 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxProbeChunkSize ProbeMaxChunkSize 探测的上限，超过该数量的单次调用在实践中没有意义
+const maxProbeChunkSize = 4096
+
+// ProbeMaxChunkSize 探测节点单次调用最多能接受多少个token，并把结果缓存为后续分批查询的批次大小
+// （优先于 WithChunkSize）
+//
+// 用sampleToken重复n次构造请求，先尝试上限4096，失败后在[1, 4096]上二分查找，
+// 最多约13次合约调用，且请求越往后越小；任何错误（包括 Config.CallTimeout 等单次调用的超时）都视为该数量不被接受，
+// 只有ctx取消、到期或客户端关闭时中止探测并返回错误。
+// 调用会消耗服务商的请求配额，建议在服务启动时执行一次。
+// sampleToken 应是一个正常的ERC20，1个token也失败时返回错误
+func (c *MultiTokenQueryClient) ProbeMaxChunkSize(ctx context.Context, userAddress common.Address, sampleToken common.Address) (int, error) {
+	accepts := func(n int) (bool, error) {
+		tokens := make([]common.Address, n)
+		for i := range tokens {
+			tokens[i] = sampleToken
+		}
+		opts, err := c.latestOpts(ctx)
+		if err != nil {
+			return false, err
+		}
+		_, err = c.queryMultipleTokensOnce(opts, userAddress, tokens)
+		// 只有调用方的ctx结束或客户端关闭才中止探测；单次调用自身的超时说明该数量太大，按不被接受继续二分
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return false, ctxErr
+		}
+		if errors.Is(err, ErrClientClosed) {
+			return false, err
+		}
+		if err != nil {
+			c.logger.Debug("探测批次大小失败", "tokens", n, "err", err)
+		}
+		return err == nil, nil
+	}
+
+	// lo 为已知可接受的最大数量，hi 为已知不可接受的最小数量
+	lo, hi := 0, maxProbeChunkSize+1
+	ok, err := accepts(maxProbeChunkSize)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		lo = maxProbeChunkSize
+	} else {
+		hi = maxProbeChunkSize
+	}
+
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		ok, err := accepts(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	if lo == 0 {
+		return 0, fmt.Errorf("单个token的查询也失败，请检查示例token %s", sampleToken.Hex())
+	}
+
+	c.probedChunkSize.Store(int64(lo))
+	c.logger.Info("探测到节点单次调用的最大token数", "chunkSize", lo)
+	return lo, nil
+}
+
+// effectiveChunkSize 分批查询使用的批次大小：探测结果优先，其次是 WithChunkSize
+func (c *MultiTokenQueryClient) effectiveChunkSize() int {
+	if probed := c.probedChunkSize.Load(); probed > 0 {
+		return int(probed)
+	}
+	return c.chunkSize
+}
@@ -0,0 +1,44 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestProbeMaxChunkSizeTreatsCallTimeoutAsRejected(t *testing.T) {
+	client, node := newFakeClient(t, Config{CallTimeout: 200 * time.Millisecond}.apply)
+	sample := node.addTokens(1)[0]
+	// queryMultipleTokens 的calldata为选择器加上 user、数组偏移、数组长度和n个地址
+	node.setHook(func(to common.Address, data []byte) ([]byte, error) {
+		if to == fakeContract && (len(data)-4)/32-3 > 100 {
+			time.Sleep(400 * time.Millisecond)
+		}
+		return nil, nil
+	})
+
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	size, err := client.ProbeMaxChunkSize(context.Background(), user, sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 100 {
+		t.Fatalf("探测结果 = %d，期望 100", size)
+	}
+}
+
+func TestProbeMaxChunkSizeAbortsOnCallerDeadline(t *testing.T) {
+	client, node := newFakeClient(t)
+	sample := node.addTokens(1)[0]
+	node.setLatency(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	if _, err := client.ProbeMaxChunkSize(ctx, user, sample); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("错误 = %v，期望 context.DeadlineExceeded", err)
+	}
+}
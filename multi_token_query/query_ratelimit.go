@@ -0,0 +1,57 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// rateLimiter 令牌桶限流器，每 interval 补充一个令牌，最多积攒 burst 个
+//
+// 按GCRA记录下一个令牌的理论到达时间，不需要后台goroutine
+type rateLimiter struct {
+	interval time.Duration
+	burst    time.Duration // burst-1 个令牌对应的时长，即允许提前使用的额度
+
+	mu  sync.Mutex
+	tat time.Time // 理论到达时间
+}
+
+// newRateLimiter 创建每秒 perSecond 个令牌的限流器，burst 小于1时按1处理
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	interval := time.Duration(float64(time.Second) / perSecond)
+	return &rateLimiter{interval: interval, burst: time.Duration(burst-1) * interval}
+}
+
+// Wait 等待一个令牌，ctx取消或等待时间超过ctx的截止时间时返回错误
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	wait := tat.Sub(now) - l.burst
+	if deadline, ok := ctx.Deadline(); ok && wait > 0 && now.Add(wait).After(deadline) {
+		l.mu.Unlock()
+		return fmt.Errorf("限流需要等待%s，超过调用的截止时间: %w", wait, context.DeadlineExceeded)
+	}
+	l.tat = tat.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
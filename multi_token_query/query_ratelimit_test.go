@@ -0,0 +1,32 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(100, 3)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 前3个令牌来自突发额度，后2个各需等待约10ms
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("5次等待用时%s，期望至少约20ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	limited := newRateLimiter(1, 1)
+	limited.Wait(context.Background())
+	if err := limited.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("错误 = %v，期望 context.DeadlineExceeded", err)
+	}
+}
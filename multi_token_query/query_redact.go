@@ -0,0 +1,103 @@
+package contracts
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RedactRPCURL 隐去节点地址中的敏感部分，只保留协议和主机名，如
+// https://mainnet.infura.io/v3/<project-id> -> https://mainnet.infura.io/***
+//
+// 服务商通常把API key放在路径、查询参数或用户信息中，这些部分统一替换为***；没有这些部分的地址原样返回，
+// IPC文件路径等没有主机名的地址也原样返回。客户端写入错误和日志的节点地址都经过该函数处理
+func RedactRPCURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "***"
+	}
+	if u.Host == "" {
+		if u.Scheme == "" {
+			return rawURL
+		}
+		return u.Scheme + "://***"
+	}
+
+	redacted := u.Scheme + "://" + u.Host
+	if u.User != nil || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		redacted += "/***"
+	}
+	return redacted
+}
+
+// redactURLInError 把错误信息中出现的完整节点地址替换为 RedactRPCURL 的结果，Unwrap 仍返回原始错误
+func redactURLInError(err error, rawURL string) error {
+	return &redactedError{msg: strings.ReplaceAll(err.Error(), rawURL, RedactRPCURL(rawURL)), err: err}
+}
+
+// redactedError 替换了错误信息、保留原始错误链的error
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// dialRPC 通过transport连接节点
+// HTTP(S)地址经 redactedURLTransport 连接，RPC客户端和它返回的错误中只出现隐去敏感部分的地址
+func dialRPC(rpcURL string, transport http.RoundTripper) (*rpc.Client, error) {
+	dialURL := rpcURL
+	if u, err := url.Parse(rpcURL); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		dialURL = RedactRPCURL(rpcURL)
+		transport = &redactedURLTransport{target: u, next: transport}
+	}
+
+	client, err := rpc.DialOptions(context.Background(), dialURL, rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		return nil, redactURLInError(err, rpcURL)
+	}
+	return client, nil
+}
+
+// redactedURLTransport 让RPC客户端只持有隐去敏感部分的地址，发送请求时才换成真实地址
+//
+// net/http 返回的 *url.Error 和go-ethereum的错误信息中带有请求地址，
+// 这样所有调用路径的错误中出现的都是隐去后的地址，不必在每处做替换
+type redactedURLTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *redactedURLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	target := *t.target
+	req.URL = &target
+	req.Host = target.Host
+	// http.Client 只对它看到的（已隐去用户信息的）地址设置Basic认证，这里按真实地址补上
+	if target.User != nil && req.Header.Get("Authorization") == "" {
+		password, _ := target.User.Password()
+		req.SetBasicAuth(target.User.Username(), password)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// LogValue 实现 slog.LogValuer，记录配置时节点地址经过 RedactRPCURL 处理
+func (cfg Config) LogValue() slog.Value {
+	urls := make([]string, len(cfg.RPCURLs))
+	for i, u := range cfg.RPCURLs {
+		urls[i] = RedactRPCURL(u)
+	}
+	return slog.GroupValue(
+		slog.Any("rpcUrls", urls),
+		slog.String("contractAddress", cfg.ContractAddress),
+		slog.Uint64("chainId", cfg.ChainID),
+		slog.Duration("callTimeout", cfg.CallTimeout),
+	)
+}
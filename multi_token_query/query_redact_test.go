@@ -0,0 +1,63 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const testAPIKey = "0123456789abcdef0123456789abcdef"
+
+func TestRedactURLInErrorKeepsChain(t *testing.T) {
+	rawURL := "https://mainnet.infura.io/v3/" + testAPIKey
+	cause := &url.Error{Op: "Post", URL: rawURL, Err: syscall.ECONNREFUSED}
+
+	err := redactURLInError(cause, rawURL)
+	if strings.Contains(err.Error(), testAPIKey) {
+		t.Fatalf("错误信息中含有API key: %v", err)
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("redact之后丢失了原始错误: %v", err)
+	}
+}
+
+func TestDialErrorOmitsAPIKey(t *testing.T) {
+	urls := []string{
+		"ws://127.0.0.1:1/v3/" + testAPIKey,
+		"ws://user:" + testAPIKey + "@127.0.0.1:1/",
+	}
+	for _, rawURL := range urls {
+		_, err := NewMultiTokenQueryClient(rawURL, fakeContract)
+		if err == nil {
+			t.Fatalf("%s: 期望连接失败", RedactRPCURL(rawURL))
+		}
+		if strings.Contains(err.Error(), testAPIKey) {
+			t.Errorf("连接错误中含有API key: %v", err)
+		}
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			t.Errorf("连接错误丢失了原始错误: %v", err)
+		}
+	}
+}
+
+func TestHTTPCallErrorOmitsAPIKey(t *testing.T) {
+	client, err := NewMultiTokenQueryClient("http://127.0.0.1:1/v3/"+testAPIKey, fakeContract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	_, err = client.QueryMultipleTokens(context.Background(), common.Address{}, nil)
+	if err == nil {
+		t.Fatal("期望调用失败")
+	}
+	if strings.Contains(err.Error(), testAPIKey) {
+		t.Fatalf("调用错误中含有API key: %v", err)
+	}
+}
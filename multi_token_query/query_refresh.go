@@ -0,0 +1,60 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// RefreshCall 查询前需要发送的状态刷新交易（如结算利息）
+type RefreshCall struct {
+	Contract common.Address
+	ABI      abi.ABI
+	Method   string
+	Args     []interface{}
+}
+
+// WithRefreshCall 配置 RefreshAndQuery 发送的刷新交易，只读查询方法不受影响
+func WithRefreshCall(refresh RefreshCall) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.refresh = &refresh
+	}
+}
+
+// RefreshAndQuery 先发送刷新交易并等待上链，再在交易所在区块上执行正常的只读查询
+//
+// 与其他查询方法不同，这里会签名并广播一笔真实交易，消耗gas。
+// 只有少数需要先调用状态修改函数才能读到正确数据的合约才需要使用
+func (c *MultiTokenQueryClient) RefreshAndQuery(ctx context.Context, auth *bind.TransactOpts, userAddress common.Address, tokenAddresses []common.Address) (*QueryResult, error) {
+	if c.refresh == nil {
+		return nil, errors.New("未配置刷新交易，请使用 WithRefreshCall")
+	}
+	if auth == nil {
+		return nil, errors.New("发送刷新交易需要签名参数")
+	}
+
+	contract := bind.NewBoundContract(c.refresh.Contract, c.refresh.ABI, c.client, c.client, c.client)
+	opts := *auth
+	opts.Context = ctx
+
+	tx, err := contract.Transact(&opts, c.refresh.Method, c.refresh.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("发送刷新交易失败: %w", err)
+	}
+
+	receipt, err := bind.WaitMined(ctx, c.client, tx)
+	if err != nil {
+		return nil, fmt.Errorf("等待刷新交易 %s 上链失败: %w", tx.Hash().Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("刷新交易 %s 执行失败", tx.Hash().Hex())
+	}
+
+	// 固定在交易所在区块查询，避免负载均衡后的节点还没同步到该区块
+	return c.queryResultAt(&bind.CallOpts{Context: ctx, BlockNumber: receipt.BlockNumber}, userAddress, tokenAddresses)
+}
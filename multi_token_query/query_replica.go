@@ -0,0 +1,47 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+)
+
+// WithReadReplicas 把合约调用发往readURLs中的只读副本，构造函数传入的节点作为主节点
+//
+// 本客户端的合约调用都是只读的 eth_call，全部可以由副本处理。路由策略：
+//   - 合约调用在副本之间轮询；副本调用失败时本次调用改发主节点（主节点上仍按 WithPoolSize、WithCircuitBreaker 选择连接）
+//   - revert和调用方ctx的取消、到期与节点无关，直接返回，不切换到主节点
+//   - 单次调用超时（Config.CallTimeout、ScaledTimeoutMiddleware）到期而调用方的ctx仍然有效时视为副本太慢，
+//     改发主节点，主节点上的调用重新计时
+//   - 启用 WithCircuitBreaker 时每个副本也有熔断器，熔断的副本被跳过，全部熔断时直接使用主节点
+//   - 链ID检查（Config.ChainID）、区块号和区块头查询、totalSupply等直接发出的请求固定使用主节点，
+//     主节点因此也承担健康检查和对齐区块的角色
+//
+// 副本略落后于主节点时，查询latest得到的区块可能比主节点旧；需要严格一致时配合 WithConsistentSnapshot 使用，
+// 固定的区块由主节点解析，副本尚未同步到该区块时调用失败并转到主节点
+func WithReadReplicas(readURLs ...string) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.replicaURLs = append(c.replicaURLs, readURLs...)
+	}
+}
+
+// availableReplica 轮询选出一个未熔断的副本，没有配置副本或全部熔断时返回nil
+func (c *MultiTokenQueryClient) availableReplica() *poolConn {
+	for range c.replicas {
+		conn := c.replicas[c.nextReplica.Add(1)%uint64(len(c.replicas))]
+		if conn.breaker == nil || conn.breaker.allow() {
+			return conn
+		}
+	}
+	return nil
+}
+
+// replicaFailover 判断副本上的调用失败后是否改用主节点，ctx为副本调用使用的ctx
+func replicaFailover(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, ErrRevert) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return !callerDone(ctx)
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// newReplicaClient 创建主节点和一个只读副本，副本上的 eth_call 延迟 replicaLatency
+func newReplicaClient(t *testing.T, replicaLatency time.Duration, opts ...Option) (*MultiTokenQueryClient, *fakeNode, *fakeNode, []common.Address) {
+	t.Helper()
+	replica := newFakeNode()
+	replica.setLatency(replicaLatency)
+	client, primary := newFakeClient(t, append([]Option{WithReadReplicas(replica.start(t))}, opts...)...)
+	tokens := primary.addTokens(2)
+	for _, addr := range tokens {
+		replica.setToken(addr, primary.tokens[addr])
+	}
+	return client, primary, replica, tokens
+}
+
+func TestReplicaFailoverOnCallTimeout(t *testing.T) {
+	client, primary, replica, tokens := newReplicaClient(t, time.Second, WithMiddleware(TimeoutMiddleware(100*time.Millisecond)))
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	start := time.Now()
+	result, err := client.QueryMultipleTokens(context.Background(), user, tokens)
+	if err != nil {
+		t.Fatalf("副本超时后应改发主节点，得到 %v", err)
+	}
+	if len(result.Tokens) != 2 || replica.calls.Load() == 0 || primary.calls.Load() == 0 {
+		t.Errorf("副本调用%d次，主节点调用%d次", replica.calls.Load(), primary.calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("耗时 %s，主节点上的调用应重新计时而不是等副本", elapsed)
+	}
+}
+
+func TestReplicaNoFailoverWhenCallerDone(t *testing.T) {
+	client, primary, _, tokens := newReplicaClient(t, time.Second)
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := client.QueryMultipleTokens(ctx, user, tokens)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v，期望调用方的截止时间", err)
+	}
+	if n := primary.calls.Load(); n != 0 {
+		t.Errorf("调用方的ctx到期后不应改发主节点，主节点调用了%d次", n)
+	}
+}
@@ -0,0 +1,98 @@
+package contracts
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// ReportView 面向报表模板的查询结果，所有字段都已格式化为字符串，模板中无需再做计算
+type ReportView struct {
+	QueryAddress string
+	// ENSName 查询地址的ENS名称，仅在启用 WithENSNames 时有值
+	ENSName     string
+	BlockNumber string
+	// Timestamp 区块时间，UTC的RFC3339格式，时间戳无效时为空
+	Timestamp string
+	// TotalUSD 有价格的持仓的美元总价值，保留两位小数；没有任何token有价格时为空
+	TotalUSD string
+	// HasUnpriced 存在没有价格的token，TotalUSD 和百分比不包含这些token
+	HasUnpriced bool
+	Tokens      []TokenView
+}
+
+// TokenView 报表中的单个token
+type TokenView struct {
+	Address string
+	Symbol  string
+	// Balance 按decimals换算后的精确余额，末尾多余的0会被去掉
+	Balance  string
+	Decimals uint8
+	// PriceUSD、ValueUSD、Percent 在没有价格时为空
+	PriceUSD string
+	ValueUSD string
+	// Percent 占有价格持仓总价值的百分比，保留两位小数，如 "12.34"
+	Percent string
+}
+
+// ToReportView 查询价格并生成报表视图，token按结果中的顺序排列
+// 预言机没有价格的token对应的美元字段留空；其他价格查询错误直接返回
+func (r *QueryResult) ToReportView(ctx context.Context, oracle PriceOracle) (*ReportView, error) {
+	view := &ReportView{
+		QueryAddress: r.QueryAddress.Hex(),
+		ENSName:      r.ENSName,
+		Tokens:       make([]TokenView, len(r.Tokens)),
+	}
+	if r.BlockNumber != nil {
+		view.BlockNumber = r.BlockNumber.String()
+	}
+	if t, ok := timestampToTime(r.Timestamp); ok {
+		view.Timestamp = t.UTC().Format(time.RFC3339)
+	}
+
+	prices, unpriced, err := pricesFor(ctx, oracle, tokenAddresses(r.Tokens))
+	if err != nil {
+		return nil, err
+	}
+	view.HasUnpriced = len(unpriced) > 0
+
+	values := make([]*big.Float, len(r.Tokens))
+	var total *big.Float
+	for i, token := range r.Tokens {
+		view.Tokens[i] = TokenView{
+			Address:  token.TokenAddress.Hex(),
+			Symbol:   token.Symbol,
+			Balance:  formatBalance(token),
+			Decimals: token.Decimals,
+		}
+
+		price, ok := prices[token.TokenAddress]
+		if !ok {
+			continue
+		}
+
+		values[i] = new(big.Float).Mul(token.TokenAmount(), price)
+		view.Tokens[i].PriceUSD = price.Text('f', 2)
+		view.Tokens[i].ValueUSD = values[i].Text('f', 2)
+		if total == nil {
+			total = new(big.Float)
+		}
+		total.Add(total, values[i])
+	}
+
+	if total == nil {
+		return view, nil
+	}
+	view.TotalUSD = total.Text('f', 2)
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		percent := new(big.Float)
+		if total.Sign() != 0 {
+			percent.Quo(new(big.Float).Mul(value, big.NewFloat(100)), total)
+		}
+		view.Tokens[i].Percent = percent.Text('f', 2)
+	}
+	return view, nil
+}
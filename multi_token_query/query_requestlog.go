@@ -0,0 +1,162 @@
+package contracts
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RequestRecord 请求日志中的一条记录，对应一次逻辑上的合约调用（重试不重复记录）
+type RequestRecord struct {
+	Time   time.Time        `json:"time"`
+	Method string           `json:"method"`
+	User   common.Address   `json:"user"`
+	Tokens []common.Address `json:"tokens"`
+	// Block 调用的区块：十进制区块号、"latest" 或 "pending"
+	Block string `json:"block"`
+	// Duration 调用耗时
+	Duration time.Duration `json:"duration"`
+	// Error 调用失败时的错误信息，成功时为空
+	Error string `json:"error,omitempty"`
+}
+
+// WithRequestLog 把每次合约调用的输入（方法、用户、token、区块）以换行分隔的JSON写入w，
+// 生产环境中某次查询出现问题时，可以在本地用 ReadRequestLog 读出记录、用 ReplayRequest 按原样重放
+//
+// 记录中不包含节点地址，错误信息中的节点地址已经过 RedactRPCURL 处理；用户地址原样记录，
+// 否则无法重放，日志应按包含用户数据的方式保管。
+// 写入总量达到maxBytes后停止记录（maxBytes<=0 时默认1MiB），避免日志无限增长；w 需调用方负责轮转和关闭
+func WithRequestLog(w io.Writer, maxBytes int64) Option {
+	return func(c *MultiTokenQueryClient) {
+		if maxBytes <= 0 {
+			maxBytes = 1 << 20
+		}
+		c.requestLog = &requestLog{w: w, limit: maxBytes}
+	}
+}
+
+// requestLog 请求日志的写入端，并发安全
+type requestLog struct {
+	mu      sync.Mutex
+	w       io.Writer
+	limit   int64
+	written int64
+	full    bool
+}
+
+// write 写入一条记录，超过大小限制时丢弃，返回是否是第一次达到限制
+func (l *requestLog) write(record RequestRecord) (becameFull bool) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return false
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.full {
+		return false
+	}
+	if l.written+int64(len(line)) > l.limit {
+		l.full = true
+		return true
+	}
+	n, _ := l.w.Write(line)
+	l.written += int64(n)
+	return false
+}
+
+// requestLogMiddleware 记录每次调用的输入和结果
+func (c *MultiTokenQueryClient) requestLogMiddleware() Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+			record := RequestRecord{Time: time.Now().UTC(), Method: method, Block: blockLabel(opts)}
+			if len(params) > 0 {
+				record.User, _ = params[0].(common.Address)
+			}
+			record.Tokens = callTokens(params)
+
+			err := next(opts, results, method, params...)
+			record.Duration = time.Since(record.Time)
+			if err != nil {
+				record.Error = err.Error()
+			}
+			if c.requestLog.write(record) {
+				c.logger.Warn("请求日志达到大小上限，停止记录", "limit", c.requestLog.limit)
+			}
+			return err
+		}
+	}
+}
+
+// blockLabel 返回请求日志中记录的区块
+func blockLabel(opts *bind.CallOpts) string {
+	if opts.Pending {
+		return "pending"
+	}
+	if opts.BlockNumber == nil {
+		return "latest"
+	}
+	return opts.BlockNumber.String()
+}
+
+// ReadRequestLog 逐条读取 WithRequestLog 写出的记录，fn 返回错误时停止读取并返回该错误
+func ReadRequestLog(r io.Reader, fn func(RequestRecord) error) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for i := 0; ; i++ {
+		var record RequestRecord
+		if err := dec.Decode(&record); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("解码第%d条请求记录失败: %v", i, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// ReplayRequest 按记录中的方法、参数和区块重新发起调用，经过与正常查询相同的调用链，返回ABI解码后的原始结果
+// 记录的区块为 "latest" 时查询当前最新区块，结果可能与当时不同；固定区块的记录需要节点仍保留该区块的状态
+func (c *MultiTokenQueryClient) ReplayRequest(ctx context.Context, record RequestRecord) ([]interface{}, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	switch record.Block {
+	case "pending":
+		opts.Pending = true
+	case "latest", "":
+	default:
+		block, ok := new(big.Int).SetString(record.Block, 10)
+		if !ok {
+			return nil, fmt.Errorf("请求记录的区块无效: %q", record.Block)
+		}
+		opts.BlockNumber = block
+	}
+
+	var params []interface{}
+	switch record.Method {
+	case "queryMultipleTokens", "queryBalances":
+		params = []interface{}{record.User, record.Tokens}
+	case "querySingleToken":
+		if len(record.Tokens) != 1 {
+			return nil, fmt.Errorf("querySingleToken的请求记录应包含1个token，实际为%d个", len(record.Tokens))
+		}
+		params = []interface{}{record.User, record.Tokens[0]}
+	default:
+		return nil, fmt.Errorf("不支持重放方法%s", record.Method)
+	}
+
+	var results []interface{}
+	if err := c.call(opts, &results, record.Method, params...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
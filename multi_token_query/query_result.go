@@ -0,0 +1,215 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NormalizedBalances 把所有token余额换算到统一的小数位数base上，便于跨token比较和求和
+// decimals小于base时乘以10的幂（精确），大于base时除以10的幂，超出base精度的部分直接截断（向零取整）。
+// 余额为nil的token按0处理
+func (r *QueryResult) NormalizedBalances(base uint8) map[common.Address]*big.Int {
+	normalized := make(map[common.Address]*big.Int, len(r.Tokens))
+	for _, token := range r.Tokens {
+		normalized[token.TokenAddress] = scaleDecimals(token.Balance, token.Decimals, base)
+	}
+	return normalized
+}
+
+// scaleDecimals 把amount从from位小数换算到to位小数，返回新的 big.Int
+func scaleDecimals(amount *big.Int, from, to uint8) *big.Int {
+	if amount == nil {
+		return new(big.Int)
+	}
+
+	switch {
+	case from < to:
+		factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(to-from)), nil)
+		return new(big.Int).Mul(amount, factor)
+	case from > to:
+		factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(from-to)), nil)
+		return new(big.Int).Quo(amount, factor)
+	default:
+		return new(big.Int).Set(amount)
+	}
+}
+
+// AggregateDecimals AggregateBySymbol 汇总时统一使用的小数位数
+const AggregateDecimals = 18
+
+// AggregateBySymbol 把symbol相同的token余额相加，例如把多个跨链桥版本的USDC合计为一个总额
+// 各token先换算到 AggregateDecimals（18）位小数再相加，返回值也以18位小数表示；
+// decimals大于18的token换算时会截断多余的精度。这里假设symbol相同即为同一资产，
+// 诈骗token可能冒用知名symbol，建议先配合 WithDenylist/WithAllowlist 过滤
+func (r *QueryResult) AggregateBySymbol() map[string]*big.Int {
+	totals := make(map[string]*big.Int)
+	for _, token := range r.Tokens {
+		amount := scaleDecimals(token.Balance, token.Decimals, AggregateDecimals)
+		if total, ok := totals[token.Symbol]; ok {
+			total.Add(total, amount)
+		} else {
+			totals[token.Symbol] = amount
+		}
+	}
+	return totals
+}
+
+// Sort 按less对Tokens原地排序，排序是稳定的（相等的token保持原有顺序）
+func (r *QueryResult) Sort(less func(a, b TokenInfo) bool) {
+	sort.SliceStable(r.Tokens, func(i, j int) bool {
+		return less(r.Tokens[i], r.Tokens[j])
+	})
+}
+
+// SortByBalance 按换算decimals后的数量从大到小排序，比较是精确的，不经过浮点数
+func (r *QueryResult) SortByBalance() {
+	r.Sort(func(a, b TokenInfo) bool {
+		return compareAmounts(a, b) > 0
+	})
+}
+
+// SortBySymbol 按symbol字典序从小到大排序
+func (r *QueryResult) SortBySymbol() {
+	r.Sort(func(a, b TokenInfo) bool {
+		return a.Symbol < b.Symbol
+	})
+}
+
+// compareAmounts 比较两个token换算decimals后的数量，返回-1、0、1
+func compareAmounts(a, b TokenInfo) int {
+	decimals := a.Decimals
+	if b.Decimals > decimals {
+		decimals = b.Decimals
+	}
+	return scaleDecimals(a.Balance, a.Decimals, decimals).Cmp(scaleDecimals(b.Balance, b.Decimals, decimals))
+}
+
+// PartitionDust 按原始余额阈值把token分为有意义的持仓和粉尘
+// threshold 以token的最小单位计，余额大于threshold的为有意义的持仓，其余为粉尘；threshold为nil按0处理
+func (r *QueryResult) PartitionDust(threshold *big.Int) (meaningful, dust []TokenInfo) {
+	if threshold == nil {
+		threshold = new(big.Int)
+	}
+	for _, token := range r.Tokens {
+		if token.Balance != nil && token.Balance.Cmp(threshold) > 0 {
+			meaningful = append(meaningful, token)
+		} else {
+			dust = append(dust, token)
+		}
+	}
+	return meaningful, dust
+}
+
+// Fingerprint 返回结果的稳定指纹（十六进制sha256），用于缓存去重和变化检测
+// 由按地址排序的 (token, 余额) 以及区块号计算，与token的顺序无关；余额为nil按0处理
+func (r *QueryResult) Fingerprint() string {
+	tokens := make([]TokenInfo, len(r.Tokens))
+	copy(tokens, r.Tokens)
+	sort.Slice(tokens, func(i, j int) bool {
+		return bytes.Compare(tokens[i].TokenAddress[:], tokens[j].TokenAddress[:]) < 0
+	})
+
+	h := sha256.New()
+	for _, token := range tokens {
+		h.Write(token.TokenAddress[:])
+		balance := token.Balance
+		if balance == nil {
+			balance = new(big.Int)
+		}
+		h.Write([]byte(balance.String()))
+		h.Write([]byte{'\n'})
+	}
+	if r.BlockNumber != nil {
+		h.Write([]byte(r.BlockNumber.String()))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChangedTokens 返回两次查询结果之间余额发生变化的token地址，包括只在其中一次结果中出现的token
+// 余额为nil按0处理；old或new为nil时视为没有任何token。返回的地址按字节序排序，结果是确定的
+func ChangedTokens(old, new *QueryResult) []common.Address {
+	before := balancesByToken(old)
+	after := balancesByToken(new)
+
+	var changed []common.Address
+	for token, balance := range after {
+		if prev, ok := before[token]; !ok || prev.Cmp(balance) != 0 {
+			changed = append(changed, token)
+		}
+	}
+	for token := range before {
+		if _, ok := after[token]; !ok {
+			changed = append(changed, token)
+		}
+	}
+
+	sort.Slice(changed, func(i, j int) bool {
+		return bytes.Compare(changed[i][:], changed[j][:]) < 0
+	})
+	return changed
+}
+
+// balancesByToken 按token地址索引余额，余额为nil按0处理
+func balancesByToken(r *QueryResult) map[common.Address]*big.Int {
+	balances := make(map[common.Address]*big.Int)
+	if r == nil {
+		return balances
+	}
+	for _, token := range r.Tokens {
+		balance := token.Balance
+		if balance == nil {
+			balance = new(big.Int)
+		}
+		balances[token.TokenAddress] = balance
+	}
+	return balances
+}
+
+// FlatMap 返回 symbol 到格式化余额（按decimals换算后的精确十进制字符串）的映射，便于在模板或配置生成中使用
+// 多个token的symbol相同时，这些token的键都加上地址末8位作为后缀，如 "USDC_7eb48ee4"；
+// symbol为空时以token地址作为键
+func (r *QueryResult) FlatMap() map[string]string {
+	counts := make(map[string]int, len(r.Tokens))
+	for _, token := range r.Tokens {
+		counts[token.Symbol]++
+	}
+
+	flat := make(map[string]string, len(r.Tokens))
+	for _, token := range r.Tokens {
+		key := token.Symbol
+		switch {
+		case key == "":
+			key = token.TokenAddress.Hex()
+		case counts[key] > 1:
+			hexAddr := strings.ToLower(token.TokenAddress.Hex())
+			key += "_" + hexAddr[len(hexAddr)-8:]
+		}
+		flat[key] = formatBalance(token)
+	}
+	return flat
+}
+
+// maxQueryTimestamp 可接受的最大时间戳（9999-12-31 23:59:59 UTC），超出视为合约返回的异常值
+const maxQueryTimestamp = 253402300799
+
+// QueryTime 返回查询区块的时间
+// 时间戳缺失或超出合理范围（恶意合约可能返回如 2^64-1 这样的值，直接 Int64() 会溢出）时返回零值 time.Time，可用 IsZero 判断
+func (r *QueryResult) QueryTime() time.Time {
+	t, _ := timestampToTime(r.Timestamp)
+	return t
+}
+
+// timestampToTime 安全地把链上时间戳转换为 time.Time，ok为false表示时间戳无效
+func timestampToTime(ts *big.Int) (t time.Time, ok bool) {
+	if ts == nil || ts.Sign() < 0 || !ts.IsInt64() || ts.Int64() > maxQueryTimestamp {
+		return time.Time{}, false
+	}
+	return time.Unix(ts.Int64(), 0), true
+}
@@ -0,0 +1,75 @@
+package contracts
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestPartitionDustNilThreshold(t *testing.T) {
+	r := &QueryResult{Tokens: []TokenInfo{
+		{Symbol: "A", Balance: big.NewInt(5)},
+		{Symbol: "B", Balance: new(big.Int)},
+		{Symbol: "C"},
+	}}
+	meaningful, dust := r.PartitionDust(nil)
+	if len(meaningful) != 1 || meaningful[0].Symbol != "A" || len(dust) != 2 {
+		t.Fatalf("meaningful = %v, dust = %v", meaningful, dust)
+	}
+}
+
+func TestPartitionDustUSDNilThreshold(t *testing.T) {
+	a, b, c := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc")
+	oracle := &mapOracle{
+		prices: map[common.Address]*big.Float{a: big.NewFloat(2), b: big.NewFloat(2)},
+		calls:  map[common.Address]int{},
+	}
+	r := &QueryResult{Tokens: []TokenInfo{
+		{TokenAddress: a, Symbol: "A", Balance: big.NewInt(5)},
+		{TokenAddress: b, Symbol: "B", Balance: new(big.Int)},
+		{TokenAddress: c, Symbol: "C", Balance: big.NewInt(1)},
+		{TokenAddress: a, Symbol: "A", Balance: big.NewInt(1)},
+	}}
+	meaningful, dust, err := r.PartitionDustUSD(context.Background(), oracle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// C没有价格，归入有意义的持仓
+	if len(meaningful) != 3 || meaningful[1].Symbol != "C" || len(dust) != 1 || dust[0].Symbol != "B" {
+		t.Fatalf("meaningful = %v, dust = %v", meaningful, dust)
+	}
+	if oracle.calls[a] != 1 {
+		t.Errorf("重复的token应只查询一次价格，查询了%d次", oracle.calls[a])
+	}
+}
+
+func TestQueryTimeOverflow(t *testing.T) {
+	maxUint64 := new(big.Int).SetUint64(math.MaxUint64)
+	tests := []struct {
+		name string
+		ts   *big.Int
+		want time.Time
+	}{
+		{name: "正常", ts: big.NewInt(1700000000), want: time.Unix(1700000000, 0)},
+		{name: "上限", ts: big.NewInt(maxQueryTimestamp), want: time.Unix(maxQueryTimestamp, 0)},
+		{name: "2^64-1", ts: maxUint64},
+		{name: "超过上限", ts: big.NewInt(maxQueryTimestamp + 1)},
+		{name: "负数", ts: big.NewInt(-1)},
+		{name: "nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &QueryResult{Timestamp: tt.ts}
+			if got := r.QueryTime(); !got.Equal(tt.want) {
+				t.Fatalf("QueryTime() = %v，期望 %v", got, tt.want)
+			}
+			if _, ok := timestampToTime(tt.ts); ok == tt.want.IsZero() {
+				t.Fatalf("timestampToTime ok = %v", ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package contracts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RetryPolicy 重试策略
+//
+// 同一个策略可以用在两层：
+//   - 方法层（WithRetry）：包裹每一次合约调用，能看到JSON-RPC层面的错误
+//   - 传输层（WithTransportRetry）：包裹HTTP请求，客户端发出的只读节点请求都会重试，
+//     包括 CodeAt、BalanceAt、HeaderByNumber 等，但只能看到网络错误和HTTP状态码
+//
+// 两层同时启用时，传输层已经重试过的错误（网络错误、HTTP 429/5xx）不会在方法层再次重试，
+// 避免重试次数相乘；方法层只负责传输层看不到的错误。
+type RetryPolicy struct {
+	// MaxAttempts 最大尝试次数（包括第一次），小于等于1表示不重试
+	MaxAttempts int
+	// InitialBackoff 第一次重试前的等待时间，之后每次翻倍
+	InitialBackoff time.Duration
+	// MaxBackoff 单次等待时间的上限
+	MaxBackoff time.Duration
+	// OnRetry 每次重试等待之前调用，attempt 为刚失败的尝试序号（从1开始），
+	// nextDelay 为即将等待的时间。回调在重试循环中同步执行，应尽快返回，
+	// 耗时操作（如上报指标）请自行异步处理。为nil时不调用
+	OnRetry func(attempt int, err error, nextDelay time.Duration) `json:"-" yaml:"-"`
+}
+
+// DefaultRetryPolicy 默认重试策略：最多3次，等待200ms起，最长2s
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// WithRetry 为每次合约调用启用方法层重试
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.retry = &policy
+	}
+}
+
+// WithTransportRetry 在JSON-RPC的HTTP传输层启用重试，仅对 http/https 地址生效
+// 只重试只读方法（eth_call、eth_getBlockByNumber、eth_chainId 等），eth_sendRawTransaction 等请求只发送一次
+func WithTransportRetry(policy RetryPolicy) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.transportRetry = &policy
+	}
+}
+
+// backoff 返回第attempt次重试（从1开始）前的等待时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// do 按策略执行fn，retryable 判断错误是否值得重试
+func (p RetryPolicy) do(ctx context.Context, fn func() error, retryable func(error) bool) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// call 所有合约调用的统一入口，调用依次经过 callChain 中的中间件
+func (c *MultiTokenQueryClient) call(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+	return c.callVia(c.callChain, opts, results, method, params...)
+}
+
+// callVia 与 call 相同，但经过的是调用方组装的调用链（如 probeTransfers 直接调用token合约的链）
+func (c *MultiTokenQueryClient) callVia(chain CallFunc, opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+	if c.closeCtx.Err() != nil {
+		return ErrClientClosed
+	}
+
+	ctx, stop := c.closeAware(opts.Context)
+	defer stop()
+	bound := *opts
+	bound.Context = ctx
+
+	err := chain(&bound, results, method, params...)
+	if err != nil && c.closeCtx.Err() != nil {
+		return fmt.Errorf("%w: %v", ErrClientClosed, err)
+	}
+	return err
+}
+
+// closeAware 把调用方的ctx与客户端的关闭信号合并，任一方取消都会取消返回的ctx
+// 调用结束后必须调用stop释放资源
+func (c *MultiTokenQueryClient) closeAware(ctx context.Context) (context.Context, func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	unregister := context.AfterFunc(c.closeCtx, cancel)
+	return ctx, func() {
+		unregister()
+		cancel()
+	}
+}
+
+// RetryMiddleware 按策略重试失败的调用，取消、revert、缺少历史状态和超出大小限制的错误不重试
+func RetryMiddleware(policy RetryPolicy) Middleware {
+	return retryMiddleware(policy, isRetryableError, nil)
+}
+
+// retryMiddleware 的 logger 可为nil
+func retryMiddleware(policy RetryPolicy, retryable func(error) bool, logger *slog.Logger) Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+			attempt := 0
+			return policy.do(opts.Context, func() error {
+				attempt++
+				if attempt > 1 && logger != nil {
+					logger.Debug("重试合约调用", "method", method, "attempt", attempt)
+				}
+				// 每次尝试前清空结果，避免上一次的部分结果残留
+				*results = (*results)[:0]
+				return next(opts, results, method, params...)
+			}, retryable)
+		}
+	}
+}
+
+// isRetryable 判断方法层是否应重试该错误
+func (c *MultiTokenQueryClient) isRetryable(err error) bool {
+	if !isRetryableError(err) {
+		return false
+	}
+	// 传输层已经重试过的错误不再重复重试
+	return c.transportRetry == nil || !isTransportError(err)
+}
+
+// isRetryableError 与客户端配置无关的重试判断
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// 合约revert是确定性的，重试没有意义
+	if errors.Is(err, ErrRevert) {
+		return false
+	}
+	// 熔断期间重试只会继续被拒绝
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	// 节点没有历史状态，重试也不会有
+	if isHistoricalStateError(err) {
+		return false
+	}
+	// 超出大小限制的请求原样重试依然会超限，交给 WithAdaptiveSplit 处理
+	if isSizeLimitError(err) {
+		return false
+	}
+	return true
+}
+
+// isTransportError 判断错误是否来自HTTP传输层（网络错误或非200状态码）
+func isTransportError(err error) bool {
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryTransport 带重试的 http.RoundTripper
+type retryTransport struct {
+	base   http.RoundTripper
+	policy RetryPolicy
+}
+
+func newRetryTransport(base http.RoundTripper, policy RetryPolicy) *retryTransport {
+	return &retryTransport{base: base, policy: policy}
+}
+
+// RoundTrip 实现 http.RoundTripper，对网络错误和 429/5xx 响应进行重试
+// 只有请求中的方法全部属于只读方法（见 readOnlyRPCMethods）时才重试，
+// 发送交易等有副作用的请求重试可能导致重复提交，只发送一次
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// rpc包发送的请求体不支持 GetBody，这里先读出来以便每次重新发送
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !readOnlyRequest(body) {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		return t.base.RoundTrip(req)
+	}
+
+	var resp *http.Response
+	err := t.policy.do(req.Context(), func() error {
+		// 丢弃上一次需要重试的响应
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			resp = nil
+		}
+
+		attemptReq := req.Clone(req.Context())
+		attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+		attemptReq.ContentLength = int64(len(body))
+
+		var err error
+		resp, err = t.base.RoundTrip(attemptReq)
+		if err != nil {
+			return err
+		}
+		if retryableStatus(resp.StatusCode) {
+			return errRetryableStatus
+		}
+		return nil
+	}, func(err error) bool {
+		return err == errRetryableStatus || req.Context().Err() == nil
+	})
+
+	// 重试用尽或被取消时把最后一次的响应交给rpc包处理，由它生成 rpc.HTTPError
+	if err == errRetryableStatus {
+		return resp, nil
+	}
+	return resp, err
+}
+
+var errRetryableStatus = errors.New("retryable http status")
+
+// retryableStatus 可重试的HTTP状态码：限流和网关类错误
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// readOnlyRPCMethods 没有副作用、可以安全重试的JSON-RPC方法
+var readOnlyRPCMethods = map[string]bool{
+	"eth_call":                  true,
+	"eth_blockNumber":           true,
+	"eth_chainId":               true,
+	"net_version":               true,
+	"eth_getBlockByNumber":      true,
+	"eth_getBlockByHash":        true,
+	"eth_getCode":               true,
+	"eth_getBalance":            true,
+	"eth_getStorageAt":          true,
+	"eth_getLogs":               true,
+	"eth_getTransactionByHash":  true,
+	"eth_getTransactionReceipt": true,
+	"eth_getTransactionCount":   true,
+	"eth_estimateGas":           true,
+	"eth_gasPrice":              true,
+	"eth_feeHistory":            true,
+	"eth_createAccessList":      true,
+	"alchemy_getTokenBalances":  true,
+	"alchemy_getTokenMetadata":  true,
+}
+
+// readOnlyRequest 判断请求体（单个请求或批量请求）中的方法是否都是只读方法，无法解析时按有副作用处理
+func readOnlyRequest(body []byte) bool {
+	methods, _, ok := rpcMethods(body)
+	if !ok || len(methods) == 0 {
+		return false
+	}
+	for _, method := range methods {
+		if !readOnlyRPCMethods[method] {
+			return false
+		}
+	}
+	return true
+}
+
+// rpcMethods 解析JSON-RPC请求体中的方法名，batch 表示是否为批量请求
+func rpcMethods(body []byte) (methods []string, batch bool, ok bool) {
+	type rpcRequest struct {
+		Method string `json:"method"`
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var requests []rpcRequest
+		if json.Unmarshal(trimmed, &requests) != nil {
+			return nil, true, false
+		}
+		for _, req := range requests {
+			methods = append(methods, req.Method)
+		}
+		return methods, true, true
+	}
+	var single rpcRequest
+	if json.Unmarshal(trimmed, &single) != nil {
+		return nil, false, false
+	}
+	return []string{single.Method}, false, true
+}
@@ -0,0 +1,45 @@
+package contracts
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransportOnlyRetriesReadOnlyMethods(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	transport := newRetryTransport(http.DefaultTransport, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	tests := []struct {
+		body string
+		want int32
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[]}`, 3},
+		{`[{"method":"eth_blockNumber"},{"method":"eth_chainId"}]`, 3},
+		{`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x00"]}`, 1},
+		{`[{"method":"eth_call"},{"method":"eth_sendRawTransaction"}]`, 1},
+		{`not json`, 1},
+	}
+	for _, tt := range tests {
+		attempts.Store(0)
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(tt.body))
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.body, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if got := attempts.Load(); got != tt.want {
+			t.Errorf("%s: 尝试%d次，期望%d次", tt.body, got, tt.want)
+		}
+	}
+}
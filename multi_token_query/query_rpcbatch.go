@@ -0,0 +1,132 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var selectorBalanceOf = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+
+// QueryBalancesRPCBatch 不经过查询合约，把每个token的 balanceOf 作为一条 eth_call
+// 放进同一个JSON-RPC批量请求发送，适用于没有部署查询合约的链或节点
+//
+// 所有调用固定在同一个区块上（pending模式除外）。配置了 WithChunkSize 时按其大小拆成多个批量请求，
+// 以适应服务商对单个批量请求条数的限制；批量请求的并发数为 WithBatching 的并行数，未设置时为 WithConcurrency。
+// 单个token调用失败不影响其他token：失败的位置balances为nil，errs中对应位置为错误；
+// balanceOf 没有返回任何数据的非标准token按余额0处理并输出警告，不算失败（地址上没有合约代码时仍算失败）；
+// 只有整个批量请求失败（如网络错误）时才返回err
+func (c *MultiTokenQueryClient) QueryBalancesRPCBatch(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address) (balances []*big.Int, errs []error, blockNumber *big.Int, err error) {
+	ctx, end := c.startSpan(ctx, "QueryBalancesRPCBatch", userAddress, SpanAttributes{TokenCount: len(tokenAddresses)})
+	defer func() { end(blockNumber, err) }()
+
+	ctx, stop := c.closeAware(ctx)
+	defer stop()
+
+	opts, err := c.latestOpts(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if opts, err = c.resolvePinnedOpts(opts); err != nil {
+		return nil, nil, nil, err
+	}
+	block := blockArg(opts)
+
+	data := balanceOfCalldata(userAddress)
+	outputs := make([]hexutil.Bytes, len(tokenAddresses))
+	elems := make([]rpc.BatchElem, len(tokenAddresses))
+	for i := range tokenAddresses {
+		msg := map[string]interface{}{
+			"to":   tokenAddresses[i],
+			"data": hexutil.Bytes(data),
+		}
+		args := []interface{}{msg, block}
+		if c.stateOverride != nil {
+			args = append(args, c.stateOverride)
+		}
+		elems[i] = rpc.BatchElem{Method: "eth_call", Args: args, Result: &outputs[i]}
+	}
+
+	size := len(elems)
+	if c.chunkSize > 0 && c.chunkSize < size {
+		size = c.chunkSize
+	}
+	var batches [][]rpc.BatchElem
+	for start := 0; start < len(elems); start += size {
+		batches = append(batches, elems[start:min(start+size, len(elems))])
+	}
+
+	parallelism := c.concurrency
+	if c.chunkParallelism > 0 {
+		parallelism = c.chunkParallelism
+	}
+	batchErrs := make([]error, len(batches))
+	runBounded(parallelism, len(batches), func(i int) {
+		batchErrs[i] = c.conn().client.Client().BatchCallContext(ctx, batches[i])
+	})
+	if err := errors.Join(batchErrs...); err != nil {
+		if c.closeCtx.Err() != nil {
+			return nil, nil, nil, fmt.Errorf("%w: %v", ErrClientClosed, err)
+		}
+		return nil, nil, nil, fmt.Errorf("批量请求失败: %w", err)
+	}
+
+	balances = make([]*big.Int, len(tokenAddresses))
+	errs = make([]error, len(tokenAddresses))
+	for i, elem := range elems {
+		if elem.Error != nil {
+			errs[i] = classifyCallError(elem.Error)
+			continue
+		}
+		var empty bool
+		balances[i], empty, errs[i] = decodeBalanceOf(outputs[i])
+		if empty {
+			if err := c.checkEmptyBalanceOf(opts, tokenAddresses[i]); err != nil {
+				balances[i], errs[i] = nil, err
+			}
+		}
+	}
+
+	return balances, errs, opts.BlockNumber, nil
+}
+
+// balanceOfCalldata 编码 balanceOf(user) 的调用数据
+func balanceOfCalldata(user common.Address) []byte {
+	return append(append([]byte{}, selectorBalanceOf...), common.LeftPadBytes(user.Bytes(), 32)...)
+}
+
+// decodeBalanceOf 解析 balanceOf 的返回值
+// 部分非标准token的 balanceOf 不返回任何数据，按余额0处理并返回empty=true；有数据但不足32字节时返回错误
+func decodeBalanceOf(data []byte) (balance *big.Int, empty bool, err error) {
+	switch {
+	case len(data) == 0:
+		return new(big.Int), true, nil
+	case len(data) < 32:
+		return nil, false, fmt.Errorf("balanceOf返回数据不完整: %d字节", len(data))
+	default:
+		return new(big.Int).SetBytes(data[:32]), false, nil
+	}
+}
+
+// checkEmptyBalanceOf 在 balanceOf 没有返回数据时确认地址上有合约代码：
+// 对没有代码的地址（如填错的token地址）做eth_call同样没有返回数据，这种情况返回错误而不是按余额0处理
+func (c *MultiTokenQueryClient) checkEmptyBalanceOf(opts *bind.CallOpts, token common.Address) error {
+	ctx, stop := c.closeAware(opts.Context)
+	defer stop()
+	code, err := c.conn().client.CodeAt(ctx, token, opts.BlockNumber)
+	if err != nil {
+		return fmt.Errorf("查询合约代码失败: %w", wrapRPCError(err))
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("地址%s上没有合约代码", token.Hex())
+	}
+	c.logger.Warn("token的balanceOf没有返回数据，余额按0处理", "token", token.Hex())
+	return nil
+}
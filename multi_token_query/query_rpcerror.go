@@ -0,0 +1,78 @@
+package contracts
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// 常见的JSON-RPC错误码，前五个为JSON-RPC 2.0标准，-32000到-32005为EIP-1474约定，3为geth的revert
+const (
+	RPCCodeParseError          = -32700
+	RPCCodeInvalidRequest      = -32600
+	RPCCodeMethodNotFound      = -32601
+	RPCCodeInvalidParams       = -32602
+	RPCCodeInternalError       = -32603
+	RPCCodeServerError         = -32000
+	RPCCodeResourceNotFound    = -32001
+	RPCCodeResourceUnavailable = -32002
+	RPCCodeTransactionRejected = -32003
+	RPCCodeMethodNotSupported  = -32004
+	RPCCodeLimitExceeded       = -32005
+	RPCCodeExecutionReverted   = 3
+)
+
+// RPCError 节点返回的JSON-RPC错误，保留原始错误码和信息，可以用 errors.As 取出后按错误码分支处理
+// 合约调用、QueryBalancesRPCBatch 等返回的错误中，只要节点返回了JSON-RPC错误就能取出；revert时同时满足 errors.Is(err, ErrRevert)
+type RPCError struct {
+	// Code JSON-RPC错误码，见 RPCCodeMethodNotFound 等常量，各服务商还会使用自定义的错误码
+	Code int
+	// Message 节点返回的错误信息
+	Message string
+	// Data 错误附带的data字段，没有时为nil
+	Data interface{}
+
+	err error
+}
+
+// Error 实现 error，与节点返回的原始错误信息一致
+func (e *RPCError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回go-ethereum的原始错误
+func (e *RPCError) Unwrap() error {
+	return e.err
+}
+
+// MethodNotFound 节点不支持该方法
+func (e *RPCError) MethodNotFound() bool {
+	return e.Code == RPCCodeMethodNotFound || e.Code == RPCCodeMethodNotSupported
+}
+
+// LimitExceeded 超出服务商的请求频率或用量限制（EIP-1474的 -32005），应降低请求速度后再试
+func (e *RPCError) LimitExceeded() bool {
+	return e.Code == RPCCodeLimitExceeded
+}
+
+// wrapRPCError 节点返回JSON-RPC错误时转换为 *RPCError，其他错误原样返回
+func wrapRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var typed *RPCError
+	if errors.As(err, &typed) {
+		return err
+	}
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return err
+	}
+
+	typed = &RPCError{Code: rpcErr.ErrorCode(), Message: rpcErr.Error(), err: err}
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		typed.Data = dataErr.ErrorData()
+	}
+	return typed
+}
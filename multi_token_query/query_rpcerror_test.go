@@ -0,0 +1,91 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestWrapRPCError(t *testing.T) {
+	plain := errors.New("connection reset")
+	if got := wrapRPCError(plain); got != plain {
+		t.Errorf("非JSON-RPC错误应原样返回，得到 %v", got)
+	}
+	if wrapRPCError(nil) != nil {
+		t.Error("nil 应返回 nil")
+	}
+
+	raw := &fakeRPCError{code: RPCCodeLimitExceeded, msg: "rate limited", data: "retry later"}
+	wrapped := wrapRPCError(fmt.Errorf("调用失败: %w", raw))
+	var typed *RPCError
+	if !errors.As(wrapped, &typed) {
+		t.Fatalf("%v 不是 *RPCError", wrapped)
+	}
+	if typed.Code != RPCCodeLimitExceeded || typed.Message != "rate limited" || typed.Data != "retry later" {
+		t.Errorf("RPCError = %+v", typed)
+	}
+	if !errors.Is(wrapped, raw) || wrapped.Error() != "调用失败: rate limited" {
+		t.Errorf("应保留原始错误链和信息，得到 %v", wrapped)
+	}
+	if again := wrapRPCError(wrapped); again != wrapped {
+		t.Error("已经转换过的错误不应再包装")
+	}
+}
+
+func TestRPCErrorCodesFromNode(t *testing.T) {
+	cases := []struct {
+		code           int
+		methodNotFound bool
+		limitExceeded  bool
+	}{
+		{RPCCodeMethodNotFound, true, false},
+		{RPCCodeMethodNotSupported, true, false},
+		{RPCCodeLimitExceeded, false, true},
+		{RPCCodeResourceUnavailable, false, false},
+		{RPCCodeInternalError, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprint(tc.code), func(t *testing.T) {
+			client, node := newFakeClient(t)
+			tokens := node.addTokens(1)
+			node.setHook(func(to common.Address, data []byte) ([]byte, error) {
+				return nil, &fakeRPCError{code: tc.code, msg: "node error", data: "0x1234"}
+			})
+
+			_, err := client.QueryMultipleTokens(context.Background(), common.HexToAddress("0x1111111111111111111111111111111111111111"), tokens)
+			var typed *RPCError
+			if !errors.As(err, &typed) {
+				t.Fatalf("err = %v，期望能取出 *RPCError", err)
+			}
+			if typed.Code != tc.code || typed.Message != "node error" || typed.Data != "0x1234" {
+				t.Errorf("RPCError = %+v", typed)
+			}
+			if typed.MethodNotFound() != tc.methodNotFound || typed.LimitExceeded() != tc.limitExceeded {
+				t.Errorf("MethodNotFound = %v, LimitExceeded = %v", typed.MethodNotFound(), typed.LimitExceeded())
+			}
+			if errors.Is(err, ErrRevert) {
+				t.Error("非revert错误码不应满足 ErrRevert")
+			}
+		})
+	}
+
+	t.Run("revert", func(t *testing.T) {
+		client, node := newFakeClient(t)
+		tokens := node.addTokens(1)
+		node.setHook(func(to common.Address, data []byte) ([]byte, error) {
+			return nil, &fakeRPCError{code: RPCCodeExecutionReverted, msg: "execution reverted", data: "0x"}
+		})
+
+		_, err := client.QueryMultipleTokens(context.Background(), common.HexToAddress("0x1111111111111111111111111111111111111111"), tokens)
+		var typed *RPCError
+		if !errors.As(err, &typed) || typed.Code != RPCCodeExecutionReverted {
+			t.Fatalf("err = %v，期望错误码为3的 *RPCError", err)
+		}
+		if !errors.Is(err, ErrRevert) {
+			t.Errorf("错误码3应满足 ErrRevert，得到 %v", err)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package contracts
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WithShareConverters 为份额类token配置份额到底层资产数量的转换函数，如 wstETH -> stETH、ERC4626金库份额 -> 资产。
+// 查询结果中这些token的Balance替换为转换后的数量，原始值保存在 Shares 中；未配置转换函数的token保持不变。
+// 转换函数在每次查询时同步调用，通常基于预先获取的汇率计算，不应发起耗时的网络请求；返回nil时保留原始余额。
+// stETH、aToken 这类balanceOf已经反映资产数量的rebasing token不需要转换
+func WithShareConverters(converters map[common.Address]func(shares *big.Int) *big.Int) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.shareConverters = converters
+	}
+}
+
+// convertShares 对配置了转换函数的token换算余额
+func (c *MultiTokenQueryClient) convertShares(result *QueryResult) {
+	if len(c.shareConverters) == 0 {
+		return
+	}
+	for i := range result.Tokens {
+		token := &result.Tokens[i]
+		convert, ok := c.shareConverters[token.TokenAddress]
+		if !ok || token.Balance == nil {
+			continue
+		}
+		if assets := convert(new(big.Int).Set(token.Balance)); assets != nil {
+			token.Shares, token.Balance = token.Balance, assets
+		}
+	}
+}
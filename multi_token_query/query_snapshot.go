@@ -0,0 +1,57 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// WithConsistentSnapshot 让批量查询（QueryMultipleTokensBatch 等）在开始时解析一次目标区块，
+// 所有用户、所有分批调用都固定在这个区块上，整批结果是同一区块的原子快照
+//
+// 目标区块为查询开始时的最新区块（配置了 WithConfirmationDepth 时为 链头-depth）。
+// 批量较大、耗时较长时，后面的调用查询的是若干区块之前的状态，普通全节点通常只保留最近约128个区块的状态，
+// 超出后会返回缺少历史状态的错误，这种情况需要连接归档节点。与 WithPendingState 不兼容
+func WithConsistentSnapshot() Option {
+	return func(c *MultiTokenQueryClient) {
+		c.pinSnapshot = true
+	}
+}
+
+// snapshotBlockKey 在ctx中保存快照区块号
+type snapshotBlockKey struct{}
+
+// Snapshot 解析一次目标区块并返回固定到该区块的ctx
+//
+// 用返回的ctx调用客户端的查询方法（QueryMultipleTokens、QueryBalances、QueryPositions、QueryBalancesRPCBatch 等）时，
+// 原本查询最新区块的调用都改为查询该区块，适合由多次调用拼成一份需要对账的快照。
+// 与 WithConsistentSnapshot 一样，ctx使用时间较长时需要归档节点；pending模式没有可固定的区块，返回错误
+func (c *MultiTokenQueryClient) Snapshot(ctx context.Context) (context.Context, *big.Int, error) {
+	if c.pending {
+		return nil, nil, errors.New("pending模式下无法固定快照区块")
+	}
+	if block, ok := snapshotBlock(ctx); ok {
+		return ctx, block, nil
+	}
+
+	opts, err := c.resolvePinnedOpts(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析快照区块失败: %w", err)
+	}
+	return context.WithValue(ctx, snapshotBlockKey{}, opts.BlockNumber), new(big.Int).Set(opts.BlockNumber), nil
+}
+
+// snapshotBlock 返回ctx中固定的快照区块号的副本
+func snapshotBlock(ctx context.Context) (*big.Int, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	block, ok := ctx.Value(snapshotBlockKey{}).(*big.Int)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Int).Set(block), true
+}
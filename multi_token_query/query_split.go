@@ -0,0 +1,62 @@
+package contracts
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// defaultSplitDepth WithAdaptiveSplit 未指定深度时的最大拆分层数，即最多拆成原来的1/16
+const defaultSplitDepth = 4
+
+// WithAdaptiveSplit 调用因响应超出服务商的大小限制失败时，把token列表对半拆开分别重试并合并结果，
+// 仍然超限的一半继续拆分，最多拆分maxDepth层（小于等于0时使用默认的4层）
+//
+// 适合单次响应上限未知的服务商，作为 WithChunkSize 静态分批的补充。
+// 拆分后的各部分固定在同一区块查询；最终采用的批次大小会记录到日志，可据此配置 WithChunkSize
+func WithAdaptiveSplit(maxDepth int) Option {
+	return func(c *MultiTokenQueryClient) {
+		if maxDepth <= 0 {
+			maxDepth = defaultSplitDepth
+		}
+		c.splitDepth = maxDepth
+	}
+}
+
+// queryMultipleTokensSplit 查询一批token，遇到大小限制错误时递归对半拆分
+func (c *MultiTokenQueryClient) queryMultipleTokensSplit(opts *bind.CallOpts, userAddress common.Address, tokenAddresses []common.Address, depth int) (*QueryResult, error) {
+	result, err := c.queryMultipleTokensOnce(opts, userAddress, tokenAddresses)
+	if err == nil || depth >= c.splitDepth || len(tokenAddresses) < 2 || !isSizeLimitError(err) {
+		return result, err
+	}
+
+	// 拆分后的两次调用必须落在同一区块
+	opts, err = c.resolvePinnedOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	half := len(tokenAddresses) / 2
+	c.logger.Info("响应超出大小限制，拆分token列表重试",
+		"tokens", len(tokenAddresses), "chunkSize", half, "depth", depth+1)
+
+	first, err := c.queryMultipleTokensSplit(opts, userAddress, tokenAddresses[:half], depth+1)
+	if err != nil {
+		return nil, err
+	}
+	second, err := c.queryMultipleTokensSplit(opts, userAddress, tokenAddresses[half:], depth+1)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &QueryResult{
+		QueryAddress: userAddress,
+		Tokens:       make([]TokenInfo, 0, len(tokenAddresses)),
+		Timestamp:    first.Timestamp,
+		BlockNumber:  first.BlockNumber,
+	}
+	for _, part := range []*QueryResult{first, second} {
+		merged.Tokens = append(merged.Tokens, part.Tokens...)
+		merged.Failures = append(merged.Failures, part.Failures...)
+	}
+	return merged, nil
+}
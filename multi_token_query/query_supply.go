@@ -0,0 +1,69 @@
+package contracts
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var selectorTotalSupply = crypto.Keccak256([]byte("totalSupply()"))[:4]
+
+// WithTotalSupply 查询时同时获取每个token的 totalSupply，填充到 TokenInfo.TotalSupply
+// 每个token额外一次 eth_call（受 WithConcurrency 限制），默认关闭
+func WithTotalSupply() Option {
+	return func(c *MultiTokenQueryClient) {
+		c.totalSupply = true
+	}
+}
+
+// fillTotalSupply 在结果所在区块（token带有自己的区块号时为该区块）查询各token的总供应量
+// 单个token查询失败只记录警告并保留nil，不影响余额结果；ctx取消或客户端关闭时返回错误
+func (c *MultiTokenQueryClient) fillTotalSupply(opts *bind.CallOpts, result *QueryResult) error {
+	ctx, stop := c.closeAware(opts.Context)
+	defer stop()
+	blockNumber := result.BlockNumber
+	runBounded(c.concurrency, len(result.Tokens), func(i int) {
+		token := &result.Tokens[i]
+		msg := ethereum.CallMsg{To: &token.TokenAddress, Data: selectorTotalSupply}
+
+		blockNumber := blockNumber
+		if token.BlockNumber != nil {
+			blockNumber = token.BlockNumber
+		}
+		client := c.conn().client
+		var data []byte
+		var err error
+		if opts.Pending {
+			data, err = client.PendingCallContract(ctx, msg)
+		} else {
+			data, err = client.CallContract(ctx, msg, blockNumber)
+		}
+		if err != nil || len(data) < 32 {
+			c.logger.Warn("查询totalSupply失败", "token", token.TokenAddress.Hex(), "error", err)
+			return
+		}
+		token.TotalSupply = new(big.Int).SetBytes(data[:32])
+	})
+
+	return ctx.Err()
+}
+
+// OwnershipFraction 返回余额占总供应量的比例，范围通常为 [0, 1]
+// 未查询总供应量或总供应量为0时返回nil
+func (t TokenInfo) OwnershipFraction() *big.Float {
+	if t.TotalSupply == nil || t.TotalSupply.Sign() == 0 {
+		return nil
+	}
+	// 份额类token的总供应量是份额总数，与换算前的份额比较
+	shares := t.Balance
+	if t.Shares != nil {
+		shares = t.Shares
+	}
+	balance := new(big.Float)
+	if shares != nil {
+		balance.SetInt(shares)
+	}
+	return balance.Quo(balance, new(big.Float).SetInt(t.TotalSupply))
+}
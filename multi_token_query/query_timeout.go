@@ -0,0 +1,77 @@
+package contracts
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ScaledTimeout 随单次调用的token数增长的超时，超时 = min(Base + PerToken × token数, Max)
+type ScaledTimeout struct {
+	// Base 与token数无关的部分，覆盖网络往返和节点的固定开销
+	Base time.Duration
+	// PerToken 每个token增加的时间
+	PerToken time.Duration
+	// Max 超时的上限
+	Max time.Duration
+}
+
+// DefaultScaledTimeout 默认取值：5s起，每个token加20ms，最长60s，即1个token约5s、1000个token约25s
+// 零值字段按该默认值处理
+var DefaultScaledTimeout = ScaledTimeout{
+	Base:     5 * time.Second,
+	PerToken: 20 * time.Millisecond,
+	Max:      60 * time.Second,
+}
+
+// For 返回查询n个token的一次调用的超时
+func (s ScaledTimeout) For(n int) time.Duration {
+	if s.Base <= 0 {
+		s.Base = DefaultScaledTimeout.Base
+	}
+	if s.PerToken <= 0 {
+		s.PerToken = DefaultScaledTimeout.PerToken
+	}
+	if s.Max <= 0 {
+		s.Max = DefaultScaledTimeout.Max
+	}
+	return min(s.Base+time.Duration(n)*s.PerToken, s.Max)
+}
+
+// WithScaledTimeout 按每次合约调用实际查询的token数设置超时（包括方法层重试的全部尝试），取代 Config.CallTimeout 的固定超时
+//
+// 固定超时对1000个token太短、对1个token又太长；拆分批次（WithChunkSize）时按每个批次的token数计算。
+// 没有token列表的调用（如批量查询多个用户的单个token）按 Base 计算。调用方的ctx更早到期时以ctx为准
+func WithScaledTimeout(s ScaledTimeout) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.scaledTimeout = &s
+	}
+}
+
+// ScaledTimeoutMiddleware 按调用参数中的token数为被包装的调用设置超时
+func ScaledTimeoutMiddleware(s ScaledTimeout) Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+			ctx, cancel := withCallTimeout(opts.Context, s.For(len(callTokens(params))))
+			defer cancel()
+			bound := *opts
+			bound.Context = ctx
+			return next(&bound, results, method, params...)
+		}
+	}
+}
+
+// callTokens 从查询合约方法的参数中取出token地址，参数依次为用户地址和token列表（或单个token）
+func callTokens(params []interface{}) []common.Address {
+	if len(params) < 2 {
+		return nil
+	}
+	switch tokens := params[1].(type) {
+	case []common.Address:
+		return tokens
+	case common.Address:
+		return []common.Address{tokens}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package contracts
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// CallTiming 单次合约调用（一次尝试）的HTTP耗时分解
+// 复用已有连接时 DNS、Connect、TLS 为0；非HTTP连接（ws、ipc）只有Total
+type CallTiming struct {
+	Method string
+	// DNS 域名解析耗时
+	DNS time.Duration
+	// Connect 建立TCP连接的耗时
+	Connect time.Duration
+	// TLS TLS握手耗时
+	TLS time.Duration
+	// FirstByte 从开始调用到收到响应第一个字节的耗时，主要反映节点的处理时间
+	FirstByte time.Duration
+	// Total 调用的总耗时，包括读取响应和解码
+	Total time.Duration
+	// ReusedConn 是否复用了连接池中的连接
+	ReusedConn bool
+	Err        error
+}
+
+// WithCallTiming 通过 httptrace 记录每次合约调用的耗时分解，每次尝试（包括重试）结束后调用fn
+// 用于定位慢节点：耗时是在DNS、建连还是节点本身。fn 在调用的goroutine中同步执行，应尽快返回。
+// 只对HTTP连接有效，会带来少量开销，默认关闭
+func WithCallTiming(fn func(CallTiming)) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.onTiming = fn
+	}
+}
+
+// TimingMiddleware 为每次调用记录 CallTiming 并交给fn
+func TimingMiddleware(fn func(CallTiming)) Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(opts *bind.CallOpts, results *[]interface{}, method string, params ...interface{}) error {
+			var mu sync.Mutex
+			timing := CallTiming{Method: method}
+			start := time.Now()
+			var dnsStart, connectStart, tlsStart time.Time
+
+			// 建连的回调可能在其他goroutine中执行
+			record := func(f func()) {
+				mu.Lock()
+				f()
+				mu.Unlock()
+			}
+			trace := &httptrace.ClientTrace{
+				DNSStart: func(httptrace.DNSStartInfo) { record(func() { dnsStart = time.Now() }) },
+				DNSDone: func(httptrace.DNSDoneInfo) {
+					record(func() { timing.DNS = time.Since(dnsStart) })
+				},
+				ConnectStart: func(string, string) { record(func() { connectStart = time.Now() }) },
+				ConnectDone: func(string, string, error) {
+					record(func() { timing.Connect = time.Since(connectStart) })
+				},
+				TLSHandshakeStart: func() { record(func() { tlsStart = time.Now() }) },
+				TLSHandshakeDone: func(tls.ConnectionState, error) {
+					record(func() { timing.TLS = time.Since(tlsStart) })
+				},
+				GotConn: func(info httptrace.GotConnInfo) {
+					record(func() { timing.ReusedConn = info.Reused })
+				},
+				GotFirstResponseByte: func() {
+					record(func() { timing.FirstByte = time.Since(start) })
+				},
+			}
+
+			traced := *opts
+			traced.Context = httptrace.WithClientTrace(opts.Context, trace)
+			err := next(&traced, results, method, params...)
+
+			mu.Lock()
+			timing.Total = time.Since(start)
+			timing.Err = err
+			result := timing
+			mu.Unlock()
+
+			fn(result)
+			return err
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package contracts
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tracer 分布式追踪钩子，每个查询方法和每次底层合约调用都会开始一个span
+// 核心包不依赖具体的追踪实现，OpenTelemetry 的实现见 otelquery 子包
+type Tracer interface {
+	// StartSpan 开始一个span，返回的ctx会传给后续的下层调用，使子span挂在该span下
+	StartSpan(ctx context.Context, name string, attrs SpanAttributes) (context.Context, SpanEnd)
+}
+
+// SpanAttributes 开始span时已知的属性
+type SpanAttributes struct {
+	// UserLabel 经过 WithAddressRedactor 脱敏后的用户地址，用作span属性；底层合约调用或批量查询时为空。
+	// 完整的用户地址不会传给 Tracer
+	UserLabel string
+	// TokenCount 查询的token数量
+	TokenCount int
+	// BlockNumber 请求的区块号，nil表示最新区块
+	BlockNumber *big.Int
+}
+
+// SpanEnd 结束span，blockNumber为数据实际对应的区块号（未知时为nil）
+type SpanEnd func(blockNumber *big.Int, err error)
+
+// WithTracer 为查询启用分布式追踪
+func WithTracer(t Tracer) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.tracer = t
+	}
+}
+
+// startSpan 未配置 Tracer 时返回原ctx和空的结束回调
+// user 为查询的用户地址（没有时传零地址），在这里统一经 walletLabel 脱敏后写入 attrs.UserLabel
+func (c *MultiTokenQueryClient) startSpan(ctx context.Context, name string, user common.Address, attrs SpanAttributes) (context.Context, SpanEnd) {
+	if c.tracer == nil {
+		return ctx, func(*big.Int, error) {}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if user != (common.Address{}) {
+		attrs.UserLabel = c.walletLabel(user)
+	}
+	return c.tracer.StartSpan(ctx, name, attrs)
+}
+
+// resultBlock 取结果的区块号，结果为nil时返回nil
+func resultBlock(r *QueryResult) *big.Int {
+	if r == nil {
+		return nil
+	}
+	return r.BlockNumber
+}
@@ -0,0 +1,41 @@
+package contracts
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans map[string]SpanAttributes
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name string, attrs SpanAttributes) (context.Context, SpanEnd) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans[name] = attrs
+	return ctx, func(*big.Int, error) {}
+}
+
+func TestSpanUserAddressRedacted(t *testing.T) {
+	tracer := &recordingTracer{spans: map[string]SpanAttributes{}}
+	client, node := newFakeClient(t, WithTracer(tracer), WithAddressRedactor(RedactAddress))
+	tokens := node.addTokens(2)
+	user := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+
+	if _, err := client.QueryMultipleTokens(context.Background(), user, tokens); err != nil {
+		t.Fatal(err)
+	}
+	attrs, ok := tracer.spans["QueryMultipleTokens"]
+	if !ok {
+		t.Fatal("没有记录到QueryMultipleTokens的span")
+	}
+	if attrs.UserLabel != RedactAddress(user) || strings.Contains(attrs.UserLabel, user.Hex()) {
+		t.Fatalf("UserLabel = %q，期望 %q", attrs.UserLabel, RedactAddress(user))
+	}
+}
@@ -0,0 +1,103 @@
+package contracts
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// WithBatching 同时设置单次调用的token数和并发执行的批次数
+//
+// 一次查询的耗时和节点压力取决于两者的乘积：chunkSize 同 WithChunkSize，parallelism 为同时在途的批次数，
+// 默认1即逐批执行。并行时先执行第一个批次，其余批次固定到它的区块上并发执行，结果仍是同一区块的快照。
+// 影响 QueryMultipleTokens、QueryBalances 的分批查询和 QueryBalancesRPCBatch 的批量请求数；
+// 不确定如何取值时可用 SuggestBatching 按服务商档位给出建议
+func WithBatching(chunkSize, parallelism int) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.chunkSize = chunkSize
+		if parallelism > 0 {
+			c.chunkParallelism = parallelism
+		}
+	}
+}
+
+// ProviderTier 节点服务商及套餐档位
+type ProviderTier string
+
+const (
+	// ProviderInfuraFree Infura免费套餐，按请求数和每秒请求数限流，适合小批次、低并发
+	ProviderInfuraFree ProviderTier = "infura-free"
+	// ProviderInfuraPaid Infura付费套餐，每秒请求数上限较高
+	ProviderInfuraPaid ProviderTier = "infura-paid"
+	// ProviderAlchemy Alchemy，按计算单元（CU）计费和限流，每次eth_call的CU与token数无关，宜用大批次减少调用次数
+	ProviderAlchemy ProviderTier = "alchemy"
+)
+
+// ProviderProfile 某个服务商档位下经验上稳妥的上限
+type ProviderProfile struct {
+	// MaxChunkSize 单次eth_call的token数上限，超过时容易遇到执行超时或返回大小限制
+	MaxChunkSize int
+	// MaxParallelism 同时在途的调用数上限，超过时容易触发HTTP 429
+	MaxParallelism int
+}
+
+// ProviderProfiles 各服务商档位的经验值，是按公开的限流规则估算的保守取值而非服务商的承诺，
+// 实际上限随套餐和服务商调整变化，可直接修改该表或用 ProbeMaxChunkSize 探测
+var ProviderProfiles = map[ProviderTier]ProviderProfile{
+	ProviderInfuraFree: {MaxChunkSize: 200, MaxParallelism: 2},
+	ProviderInfuraPaid: {MaxChunkSize: 500, MaxParallelism: 8},
+	ProviderAlchemy:    {MaxChunkSize: 500, MaxParallelism: 4},
+}
+
+// SuggestBatching 按服务商档位为tokenCount个token建议 WithBatching 的参数
+//
+// 优先减少调用次数（服务商按请求或CU计费）：批次数取不超过 MaxChunkSize 所需的最少批次，
+// 再把token平均分到各批次；并行数取批次数与 MaxParallelism 的较小值
+func SuggestBatching(tier ProviderTier, tokenCount int) (chunkSize, parallelism int, err error) {
+	profile, ok := ProviderProfiles[tier]
+	if !ok {
+		return 0, 0, fmt.Errorf("未知的服务商档位: %q", tier)
+	}
+	if tokenCount <= 0 {
+		return profile.MaxChunkSize, 1, nil
+	}
+
+	chunks := (tokenCount + profile.MaxChunkSize - 1) / profile.MaxChunkSize
+	chunkSize = (tokenCount + chunks - 1) / chunks
+	return chunkSize, min(chunks, profile.MaxParallelism), nil
+}
+
+// runChunks 对n个批次依次调用fn，fn返回该批次所在的区块号
+// 配置了 WithBatching 的并行数时，先执行第一个批次，其余批次固定到它的区块上并发执行；
+// 出错时返回序号最小的批次的错误
+func (c *MultiTokenQueryClient) runChunks(opts *bind.CallOpts, n int, fn func(opts *bind.CallOpts, i int) (*big.Int, error)) error {
+	if c.chunkParallelism <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			if _, err := fn(opts, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	block, err := fn(opts, 0)
+	if err != nil {
+		return err
+	}
+	rest := opts
+	if block != nil && !opts.Pending {
+		rest = pinnedOpts(opts, block)
+	}
+
+	errs := make([]error, n-1)
+	runBounded(c.chunkParallelism, n-1, func(i int) {
+		_, errs[i] = fn(rest, i+1)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// requiredMethods 客户端依赖的合约方法
+var requiredMethods = []string{"queryMultipleTokens", "queryBalances", "querySingleToken"}
+
+// Validate 在不访问节点的情况下检查一次批量查询的输入和客户端配置，适合在CLI或请求处理中提前给出反馈
+//
+// 检查用户和token地址不为零地址、合约地址不为零地址、ABI中包含所需的方法，以及分批等配置是否合理，
+// 所有问题通过 errors.Join 合并返回，没有问题时返回nil。
+// 重复的地址和会被 WithAllowlist/WithDenylist 过滤掉的token不算错误，只通过日志记录警告
+func (c *MultiTokenQueryClient) Validate(users, tokenAddresses []common.Address) error {
+	var errs []error
+
+	for i, addr := range c.contractAddrs {
+		if addr == (common.Address{}) {
+			errs = append(errs, fmt.Errorf("第%d个查询合约地址为零地址", i))
+		}
+	}
+	for _, method := range requiredMethods {
+		if _, ok := c.abi.Methods[method]; !ok {
+			errs = append(errs, fmt.Errorf("ABI中缺少方法%s", method))
+		}
+	}
+	if c.chunkSize < 0 {
+		errs = append(errs, fmt.Errorf("分批大小不能为负数: %d", c.chunkSize))
+	}
+
+	if len(users) == 0 {
+		errs = append(errs, errors.New("没有要查询的用户"))
+	}
+	seenUsers := make(map[common.Address]bool, len(users))
+	for i, user := range users {
+		if user == (common.Address{}) {
+			errs = append(errs, fmt.Errorf("第%d个用户地址为零地址", i))
+			continue
+		}
+		if seenUsers[user] {
+			c.logger.Warn("用户地址重复", "index", i, "user", c.walletLabel(user))
+		}
+		seenUsers[user] = true
+	}
+
+	seenTokens := make(map[common.Address]bool, len(tokenAddresses))
+	for i, token := range tokenAddresses {
+		if token == (common.Address{}) {
+			errs = append(errs, fmt.Errorf("第%d个token地址为零地址", i))
+			continue
+		}
+		if seenTokens[token] {
+			c.logger.Warn("token地址重复", "index", i, "token", token.Hex())
+		}
+		seenTokens[token] = true
+
+		if (c.allowlist != nil && !c.allowlist[token]) || c.denylist[token] {
+			c.logger.Warn("token会被白名单或黑名单过滤", "token", token.Hex())
+		}
+	}
+
+	return errors.Join(errs...)
+}
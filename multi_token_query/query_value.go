@@ -0,0 +1,218 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrPriceUnavailable 预言机没有该token的价格
+// PriceOracle 的实现在价格未知时应返回该错误（或包装该错误）
+var ErrPriceUnavailable = errors.New("价格不可用")
+
+// PriceOracle token价格来源
+type PriceOracle interface {
+	// PriceUSD 返回一个完整token单位（已按decimals换算）的美元价格
+	PriceUSD(ctx context.Context, token common.Address) (*big.Float, error)
+}
+
+// TokenAmount 返回按decimals换算后的余额，如 1500000（6位小数）返回 1.5
+func (t TokenInfo) TokenAmount() *big.Float {
+	if t.Balance == nil {
+		return new(big.Float)
+	}
+	amount := new(big.Float).SetInt(t.Balance)
+	if t.Decimals == 0 {
+		return amount
+	}
+	unit := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Decimals)), nil))
+	return amount.Quo(amount, unit)
+}
+
+// formatBalance 返回按decimals换算后的精确十进制字符串，如 1500000（6位小数）返回 "1.5"
+// 基于 big.Int 计算，不会丢失精度；末尾多余的0会被去掉
+func formatBalance(t TokenInfo) string {
+	if t.Balance == nil {
+		return "0"
+	}
+
+	digits := new(big.Int).Abs(t.Balance).String()
+	sign := ""
+	if t.Balance.Sign() < 0 {
+		sign = "-"
+	}
+	if t.Decimals == 0 {
+		return sign + digits
+	}
+
+	d := int(t.Decimals)
+	if len(digits) <= d {
+		digits = strings.Repeat("0", d-len(digits)+1) + digits
+	}
+	intPart, fracPart := digits[:len(digits)-d], strings.TrimRight(digits[len(digits)-d:], "0")
+	if fracPart == "" {
+		return sign + intPart
+	}
+	return sign + intPart + "." + fracPart
+}
+
+// TotalInToken 把结果中所有持仓折算为baseToken的数量后求和，例如以WETH计价的总资产
+// 折算汇率为 token美元价格 / baseToken美元价格，baseToken自身的余额不做换算。
+// 任何一个token缺少价格都会返回错误
+func TotalInToken(ctx context.Context, result *QueryResult, baseToken common.Address, oracle PriceOracle) (*big.Float, error) {
+	basePrice, err := oracle.PriceUSD(ctx, baseToken)
+	if err != nil {
+		return nil, fmt.Errorf("查询基准token %s 价格失败: %w", baseToken.Hex(), err)
+	}
+	if basePrice == nil || basePrice.Sign() <= 0 {
+		return nil, fmt.Errorf("基准token %s 价格无效: %v", baseToken.Hex(), basePrice)
+	}
+
+	total := new(big.Float)
+	for _, token := range result.Tokens {
+		amount := token.TokenAmount()
+		if token.TokenAddress == baseToken {
+			total.Add(total, amount)
+			continue
+		}
+
+		price, err := oracle.PriceUSD(ctx, token.TokenAddress)
+		if err != nil {
+			return nil, fmt.Errorf("查询token %s 价格失败: %w", token.TokenAddress.Hex(), err)
+		}
+		if price == nil {
+			return nil, fmt.Errorf("查询token %s 价格失败: %w", token.TokenAddress.Hex(), ErrPriceUnavailable)
+		}
+
+		value := new(big.Float).Mul(amount, price)
+		total.Add(total, value.Quo(value, basePrice))
+	}
+
+	return total, nil
+}
+
+// PartitionDustUSD 按美元价值阈值把token分为有意义的持仓和粉尘
+// 价值大于thresholdUSD的为有意义的持仓，thresholdUSD为nil时按0处理；预言机没有价格的token无法判断，归入有意义的持仓
+func (r *QueryResult) PartitionDustUSD(ctx context.Context, oracle PriceOracle, thresholdUSD *big.Float) (meaningful, dust []TokenInfo, err error) {
+	if thresholdUSD == nil {
+		thresholdUSD = new(big.Float)
+	}
+	prices, _, err := pricesFor(ctx, oracle, tokenAddresses(r.Tokens))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, token := range r.Tokens {
+		price, ok := prices[token.TokenAddress]
+		if !ok {
+			meaningful = append(meaningful, token)
+			continue
+		}
+
+		value := new(big.Float).Mul(token.TokenAmount(), price)
+		if value.Cmp(thresholdUSD) > 0 {
+			meaningful = append(meaningful, token)
+		} else {
+			dust = append(dust, token)
+		}
+	}
+	return meaningful, dust, nil
+}
+
+// pricesFor 向预言机查询tokens的美元价格，重复的地址只查询一次
+// 预言机返回 ErrPriceUnavailable 或nil价格的token不在prices中，按首次出现的顺序记录在unpriced中；
+// 其他价格查询错误直接返回
+func pricesFor(ctx context.Context, oracle PriceOracle, tokens []common.Address) (prices map[common.Address]*big.Float, unpriced []common.Address, err error) {
+	prices = make(map[common.Address]*big.Float, len(tokens))
+	seen := make(map[common.Address]bool, len(tokens))
+	for _, token := range tokens {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+
+		price, err := oracle.PriceUSD(ctx, token)
+		if errors.Is(err, ErrPriceUnavailable) || (err == nil && price == nil) {
+			unpriced = append(unpriced, token)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("查询token %s 价格失败: %w", token.Hex(), err)
+		}
+		prices[token] = price
+	}
+	return prices, unpriced, nil
+}
+
+// tokenAddresses 返回tokens的地址列表
+func tokenAddresses(tokens []TokenInfo) []common.Address {
+	addrs := make([]common.Address, len(tokens))
+	for i, token := range tokens {
+		addrs[i] = token.TokenAddress
+	}
+	return addrs
+}
+
+// ValueUSD 返回结果中所有持仓的美元总价值
+// 预言机没有价格的token不计入总价值，其地址通过unpriced返回；其他价格查询错误直接返回
+func (r *QueryResult) ValueUSD(ctx context.Context, oracle PriceOracle) (total *big.Float, unpriced []common.Address, err error) {
+	prices, unpriced, err := pricesFor(ctx, oracle, tokenAddresses(r.Tokens))
+	if err != nil {
+		return nil, nil, err
+	}
+	total = new(big.Float)
+	for _, token := range r.Tokens {
+		if price, ok := prices[token.TokenAddress]; ok {
+			total.Add(total, new(big.Float).Mul(token.TokenAmount(), price))
+		}
+	}
+	return total, unpriced, nil
+}
+
+// DefaultStablecoins 以太坊主网常见美元稳定币（USDC、USDT、DAI、FRAX、TUSD、PYUSD、LUSD），GroupStablecoins 使用的默认集合
+// 其他链上地址不同，需要通过 GroupStablecoinsIn 传入自己的集合
+var DefaultStablecoins = map[common.Address]bool{
+	common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"): true,
+	common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7"): true,
+	common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F"): true,
+	common.HexToAddress("0x853d955aCEf822Db058eb8505911ED77F175b99e"): true,
+	common.HexToAddress("0x0000000000085d4780B73119b644AE5ecd22b376"): true,
+	common.HexToAddress("0x6c3ea9036406852006290770BEdFcAbA0e23A0e8"): true,
+	common.HexToAddress("0x5f98805A4E8be255a32880FDeC7F6728C6568bA0"): true,
+}
+
+// GroupStablecoins 按 DefaultStablecoins 把稳定币合并为一个美元总额，其余token原样返回，用于组合视图中的"稳定币"分组
+func (r *QueryResult) GroupStablecoins(ctx context.Context, oracle PriceOracle) (stableTotal *big.Float, rest []TokenInfo, err error) {
+	return r.GroupStablecoinsIn(ctx, oracle, DefaultStablecoins)
+}
+
+// GroupStablecoinsIn 与 GroupStablecoins 相同，使用调用方提供的稳定币集合
+// 稳定币按预言机价格折算而不是假定1美元，以反映脱锚；预言机没有价格的稳定币无法计入总额，放入rest中；
+// 其他价格查询错误直接返回
+func (r *QueryResult) GroupStablecoinsIn(ctx context.Context, oracle PriceOracle, stablecoins map[common.Address]bool) (stableTotal *big.Float, rest []TokenInfo, err error) {
+	var stable []common.Address
+	for _, token := range r.Tokens {
+		if stablecoins[token.TokenAddress] {
+			stable = append(stable, token.TokenAddress)
+		}
+	}
+	prices, _, err := pricesFor(ctx, oracle, stable)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stableTotal = new(big.Float)
+	for _, token := range r.Tokens {
+		price, ok := prices[token.TokenAddress]
+		if !ok {
+			rest = append(rest, token)
+			continue
+		}
+		stableTotal.Add(stableTotal, new(big.Float).Mul(token.TokenAmount(), price))
+	}
+	return stableTotal, rest, nil
+}
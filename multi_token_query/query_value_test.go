@@ -0,0 +1,85 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// mapOracle 按map返回价格的预言机，记录每个token的查询次数
+type mapOracle struct {
+	prices map[common.Address]*big.Float
+	err    map[common.Address]error
+	calls  map[common.Address]int
+}
+
+func (o *mapOracle) PriceUSD(ctx context.Context, token common.Address) (*big.Float, error) {
+	o.calls[token]++
+	if err := o.err[token]; err != nil {
+		return nil, err
+	}
+	price, ok := o.prices[token]
+	if !ok {
+		return nil, ErrPriceUnavailable
+	}
+	return price, nil
+}
+
+func TestPricesFor(t *testing.T) {
+	a, b, c := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc")
+	oracle := &mapOracle{
+		prices: map[common.Address]*big.Float{a: big.NewFloat(2)},
+		calls:  map[common.Address]int{},
+	}
+
+	prices, unpriced, err := pricesFor(context.Background(), oracle, []common.Address{a, b, a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 1 || prices[a].Cmp(big.NewFloat(2)) != 0 {
+		t.Errorf("prices = %v", prices)
+	}
+	if len(unpriced) != 1 || unpriced[0] != b {
+		t.Errorf("unpriced = %v，期望只有 %s", unpriced, b.Hex())
+	}
+	if oracle.calls[a] != 1 || oracle.calls[b] != 1 {
+		t.Errorf("重复的地址被查询了多次: %v", oracle.calls)
+	}
+
+	boom := errors.New("预言机不可用")
+	oracle.err = map[common.Address]error{c: boom}
+	if _, _, err := pricesFor(context.Background(), oracle, []common.Address{a, c}); !errors.Is(err, boom) {
+		t.Errorf("err = %v，期望包装预言机的错误", err)
+	}
+}
+
+func TestValueUSDAndReportShareUnpriced(t *testing.T) {
+	a, b := common.HexToAddress("0xa"), common.HexToAddress("0xb")
+	oracle := &mapOracle{
+		prices: map[common.Address]*big.Float{a: big.NewFloat(2)},
+		calls:  map[common.Address]int{},
+	}
+	result := &QueryResult{Tokens: []TokenInfo{
+		{TokenAddress: a, Balance: big.NewInt(3)},
+		{TokenAddress: b, Balance: big.NewInt(5)},
+	}}
+
+	total, unpriced, err := result.ValueUSD(context.Background(), oracle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Cmp(big.NewFloat(6)) != 0 || len(unpriced) != 1 || unpriced[0] != b {
+		t.Errorf("ValueUSD = %v, %v", total, unpriced)
+	}
+
+	view, err := result.ToReportView(context.Background(), oracle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !view.HasUnpriced || view.TotalUSD != "6.00" || view.Tokens[1].ValueUSD != "" {
+		t.Errorf("报表视图 = %+v", view)
+	}
+}
@@ -0,0 +1,57 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// VerifyABI 检查客户端使用的ABI与部署的合约（包括 WithFallbackContracts 配置的备用合约）是否一致
+//
+// 对每个合约地址确认有合约代码，再以空token列表调用一次 queryMultipleTokens 并按ABI解码返回数据。
+// 调用开销很小，适合在服务启动时作为预检，提前发现ABI漂移，而不是在查询时遇到难以理解的解码错误。
+// 不会自动调用，需要时显式调用
+func (c *MultiTokenQueryClient) VerifyABI(ctx context.Context) error {
+	ctx, stop := c.closeAware(ctx)
+	defer stop()
+
+	conn := c.conn()
+	opts := &bind.CallOpts{Context: ctx}
+
+	for i, addr := range c.contractAddrs {
+		if err := c.verifyContract(conn, i, opts); err != nil {
+			return fmt.Errorf("合约%s: %w", addr.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// verifyContract 检查conn上第index个合约地址
+func (c *MultiTokenQueryClient) verifyContract(conn *poolConn, index int, opts *bind.CallOpts) error {
+	code, err := conn.client.CodeAt(opts.Context, c.contractAddrs[index], nil)
+	if err != nil {
+		return fmt.Errorf("查询合约代码失败: %w", err)
+	}
+	if len(code) == 0 {
+		return errors.New("地址上没有合约代码，请检查合约地址和所连接的链")
+	}
+
+	var results []interface{}
+	err = c.invokeContract(conn, index, opts, &results, "queryMultipleTokens", common.Address{}, []common.Address{})
+	if errors.Is(err, ErrRevert) {
+		return fmt.Errorf("调用queryMultipleTokens被revert，合约可能没有该方法，ABI与部署的合约不一致: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("调用queryMultipleTokens失败，ABI可能与部署的合约不一致: %w", err)
+	}
+	if len(results) == 0 {
+		return errors.New("queryMultipleTokens没有返回数据，ABI可能与部署的合约不一致")
+	}
+	if _, err := decodeQueryResult(results[0]); err != nil {
+		return fmt.Errorf("返回数据与ABI中的QueryResult结构不一致: %v", err)
+	}
+	return nil
+}
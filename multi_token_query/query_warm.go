@@ -0,0 +1,96 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WarmCache 首批和最大的批次大小
+const (
+	warmInitialBatch = 16
+	warmMaxBatch     = 512
+)
+
+// WithWarmCacheProgress 设置 WarmCache 的进度回调，每完成一批调用一次
+// done 为已经在注册表中的token数（包括之前已登记的），total 为去重后的token总数
+func WithWarmCacheProgress(fn func(done, total int)) Option {
+	return func(c *MultiTokenQueryClient) {
+		c.onWarmProgress = fn
+	}
+}
+
+// WarmCache 预先从链上读取tokens的symbol和decimals并登记到 WithMetadataRegistry 配置的注册表，
+// 使服务开始处理请求后的第一次查询就只需要读取余额
+//
+// 已登记的token直接跳过，因此可以重复调用，也可以在失败或超时后再次调用从断点继续。
+// 批次大小从16开始逐批翻倍，最大512（配置了 WithChunkSize 或 ProbeMaxChunkSize 时不超过其值），
+// 首批很快完成，节点正常时后续批次迅速放大。单个token读取失败（启用 WithPerTokenFallback 时）不会登记，
+// 全部批次完成后通过 errors.Join 返回；整批失败时立即返回错误
+func (c *MultiTokenQueryClient) WarmCache(ctx context.Context, tokens []common.Address) (err error) {
+	if c.registry == nil {
+		return errors.New("未配置元数据注册表，请使用 WithMetadataRegistry")
+	}
+
+	ctx, end := c.startSpan(ctx, "WarmCache", common.Address{}, SpanAttributes{TokenCount: len(tokens)})
+	defer func() { end(nil, err) }()
+
+	seen := make(map[common.Address]bool, len(tokens))
+	var pending []common.Address
+	for _, token := range tokens {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+		if _, ok := c.registry.Get(token); !ok {
+			pending = append(pending, token)
+		}
+	}
+
+	total := len(seen)
+	done := total - len(pending)
+	c.reportWarmProgress(done, total)
+
+	maxBatch := warmMaxBatch
+	if size := c.effectiveChunkSize(); size > 0 && size < maxBatch {
+		maxBatch = size
+	}
+
+	var failures []error
+	for batch := warmInitialBatch; len(pending) > 0; batch *= 2 {
+		if batch > maxBatch {
+			batch = maxBatch
+		}
+		n := min(batch, len(pending))
+
+		opts, err := c.latestOpts(ctx)
+		if err != nil {
+			return err
+		}
+		result, err := c.queryMultipleTokens(opts, common.Address{}, pending[:n])
+		if err != nil {
+			return fmt.Errorf("预热token元数据失败（已完成%d/%d）: %w", done, total, err)
+		}
+
+		for _, token := range result.Tokens {
+			c.registry.Set(token.TokenAddress, TokenMetadata{Symbol: token.Symbol, Decimals: token.Decimals})
+		}
+		for _, failure := range result.Failures {
+			failures = append(failures, fmt.Errorf("token %s: %w", failure.Token.Hex(), failure.Err))
+		}
+
+		done += len(result.Tokens)
+		pending = pending[n:]
+		c.reportWarmProgress(done, total)
+	}
+
+	return errors.Join(failures...)
+}
+
+func (c *MultiTokenQueryClient) reportWarmProgress(done, total int) {
+	if c.onWarmProgress != nil {
+		c.onWarmProgress(done, total)
+	}
+}
@@ -0,0 +1,111 @@
+package contracts
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// defaultBlockPollInterval 无法订阅新区块时轮询 BlockNumber 的默认间隔
+const defaultBlockPollInterval = 2 * time.Second
+
+// WithBlockPollInterval 设置 WatchBalancesByBlock 轮询区块高度的间隔，仅在无法订阅新区块（如HTTP连接）时使用
+func WithBlockPollInterval(d time.Duration) Option {
+	return func(c *MultiTokenQueryClient) {
+		if d > 0 {
+			c.blockPollInterval = d
+		}
+	}
+}
+
+// WatchBalancesByBlock 按出块节奏监控余额：链头前进时才重新查询，并把快照发送到ch
+//
+// 优先通过 SubscribeNewHead 订阅新区块（需要websocket/ipc连接），不支持时退回轮询 BlockNumber。
+// 查询总是针对当前最新的链头，查询期间出的多个区块会合并为一次查询，不会越积越多。
+// 一直运行到ctx取消或客户端 Close（返回ctx.Err()）或查询失败（返回该错误），不会关闭ch
+func (c *MultiTokenQueryClient) WatchBalancesByBlock(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address, ch chan<- *BalanceSnapshot) error {
+	ctx, stop := c.closeAware(ctx)
+	defer stop()
+	heads := make(chan *types.Header, 16)
+	sub, err := c.client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return c.pollBalancesByBlock(ctx, userAddress, tokenAddresses, ch)
+	}
+	defer sub.Unsubscribe()
+
+	var last *big.Int
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case head := <-heads:
+			// 取出已经积压的区块头，只查询最新的一个
+			for drained := false; !drained; {
+				select {
+				case next := <-heads:
+					head = next
+				default:
+					drained = true
+				}
+			}
+
+			if last != nil && head.Number.Cmp(last) <= 0 {
+				continue
+			}
+			if err := c.sendSnapshotAt(ctx, userAddress, tokenAddresses, head.Number, ch); err != nil {
+				return err
+			}
+			last = head.Number
+		}
+	}
+}
+
+// pollBalancesByBlock 通过轮询 BlockNumber 检测链头变化
+func (c *MultiTokenQueryClient) pollBalancesByBlock(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address, ch chan<- *BalanceSnapshot) error {
+	ticker := time.NewTicker(c.blockPollInterval)
+	defer ticker.Stop()
+
+	var last uint64
+	for first := true; ; first = false {
+		if !first {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+
+		head, err := c.client.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		if !first && head <= last {
+			continue
+		}
+
+		if err := c.sendSnapshotAt(ctx, userAddress, tokenAddresses, new(big.Int).SetUint64(head), ch); err != nil {
+			return err
+		}
+		last = head
+	}
+}
+
+// sendSnapshotAt 查询指定区块的余额并发送到ch
+func (c *MultiTokenQueryClient) sendSnapshotAt(ctx context.Context, userAddress common.Address, tokenAddresses []common.Address, blockNumber *big.Int, ch chan<- *BalanceSnapshot) error {
+	snapshot, err := c.QueryBalancesAtBlock(ctx, userAddress, tokenAddresses, blockNumber)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case ch <- snapshot:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
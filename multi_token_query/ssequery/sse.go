@@ -0,0 +1,72 @@
+// Package ssequery 通过 Server-Sent Events 把查询结果推送给浏览器
+// 单独成包，核心包不依赖 net/http 的服务端接口
+package ssequery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	contracts "github.com/agol586/theattic/multi_token_query"
+)
+
+// WriteResultsSSE 把resultCh中的每个结果编码为一条SSE事件写出，每条事件写完立即flush。
+// 每个结果对应一条 "event: result" 事件，data为结果的JSON；resultCh关闭后写出一条 "event: done" 事件并返回nil。
+// 客户端断开或ctx被取消时返回ctx的错误，通常传入 r.Context()。
+// w 必须实现 http.Flusher
+//
+//	ch := make(chan *contracts.QueryResult, 16)
+//	go func() {
+//		defer close(ch)
+//		client.StreamMultipleTokens(r.Context(), users, tokens, ch)
+//	}()
+//	ssequery.WriteResultsSSE(w, r.Context(), ch)
+func WriteResultsSSE(w http.ResponseWriter, ctx context.Context, resultCh <-chan *contracts.QueryResult) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New("ResponseWriter不支持flush，无法输出SSE")
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭nginx等反向代理的缓冲，保证事件及时到达
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result, ok := <-resultCh:
+			if !ok {
+				if _, err := fmt.Fprint(w, "event: done\ndata: {}\n\n"); err != nil {
+					return err
+				}
+				flusher.Flush()
+				return nil
+			}
+			if result == nil {
+				continue
+			}
+			if err := writeEvent(w, "result", result); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent 写出一条SSE事件，json.Marshal的输出不含换行，可以直接作为单行data
+func writeEvent(w http.ResponseWriter, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("编码结果失败: %v", err)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
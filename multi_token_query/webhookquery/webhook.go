@@ -0,0 +1,160 @@
+// Package webhookquery 把查询结果以JSON POST到webhook，供非Go的系统接收持仓快照
+// 单独成包，核心包不依赖对外发送HTTP请求的逻辑
+package webhookquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	contracts "github.com/agol586/theattic/multi_token_query"
+)
+
+// Option 配置 PostResultsWebhook
+type Option func(*config)
+
+type config struct {
+	client  *http.Client
+	headers http.Header
+	retry   contracts.RetryPolicy
+}
+
+// WithHeader 为请求加入一个header，如认证用的 Authorization 或签名，多次调用时依次追加
+func WithHeader(key, value string) Option {
+	return func(cfg *config) {
+		cfg.headers.Add(key, value)
+	}
+}
+
+// WithHTTPClient 使用指定的 http.Client 发送请求，默认为 http.DefaultClient
+func WithHTTPClient(client *http.Client) Option {
+	return func(cfg *config) {
+		cfg.client = client
+	}
+}
+
+// WithRetry 设置重试策略，默认为 contracts.DefaultRetryPolicy；MaxAttempts<=1 表示不重试
+func WithRetry(policy contracts.RetryPolicy) Option {
+	return func(cfg *config) {
+		cfg.retry = policy
+	}
+}
+
+// StatusError webhook返回了非2xx的状态码
+type StatusError struct {
+	StatusCode int
+	// Body 响应体的开头部分，便于排查
+	Body string
+}
+
+// Error 实现 error
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("webhook返回HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("webhook返回HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// PostResultsWebhook 把results编码为JSON数组（每个元素同 QueryResult 的JSON表示）POST到url
+//
+// 网络错误、HTTP 429 和 5xx 按重试策略重试，其他非2xx状态码直接返回 *StatusError。
+// 请求和重试等待都受ctx控制；返回的错误中的url经过 contracts.RedactRPCURL 处理，
+// webhook地址路径中常带有密钥（如Slack、Discord），不会因为记录错误而泄露
+func PostResultsWebhook(ctx context.Context, url string, results []*contracts.QueryResult, opts ...Option) error {
+	cfg := &config{client: http.DefaultClient, headers: make(http.Header), retry: contracts.DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	body, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("序列化查询结果失败: %v", err)
+	}
+
+	attempts := max(cfg.retry.MaxAttempts, 1)
+	backoff := cfg.retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err = post(ctx, cfg, url, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= attempts || !retryable(err) || ctx.Err() != nil {
+			return redact(err, url)
+		}
+		if cfg.retry.OnRetry != nil {
+			cfg.retry.OnRetry(attempt, redact(err, url), backoff)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return redact(err, url)
+		case <-timer.C:
+		}
+		backoff *= 2
+		if cfg.retry.MaxBackoff > 0 && backoff > cfg.retry.MaxBackoff {
+			backoff = cfg.retry.MaxBackoff
+		}
+	}
+}
+
+// post 发送一次请求
+func post(ctx context.Context, cfg *config, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建webhook请求失败: %v", err)
+	}
+	for key, values := range cfg.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cfg.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送webhook请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(snippet))}
+}
+
+// retryable 网络错误、429和5xx值得重试
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// redact 把错误信息中的完整url替换为隐去敏感部分的地址，*StatusError 中不含url，原样返回
+// 替换后仍可用 errors.Is 判断ctx的错误
+func redact(err error, url string) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return err
+	}
+	return &redactedError{msg: strings.ReplaceAll(err.Error(), url, contracts.RedactRPCURL(url)), err: err}
+}
+
+// redactedError 替换了错误信息、保留原始错误链的error
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }